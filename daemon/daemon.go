@@ -3,6 +3,8 @@ package daemon
 import (
 	"context"
 	"fmt"
+	"sort"
+	"sync"
 
 	"go.uber.org/fx"
 	"go.uber.org/zap"
@@ -17,11 +19,18 @@ const (
 
 var logger *zap.Logger
 
+type HealthCheck func(ctx context.Context) error
+
 type Daemon struct {
-	logger       *zap.Logger
-	scope        string
-	isReady      bool
+	logger  *zap.Logger
+	scope   string
+	isReady bool
+
+	healthMu     sync.RWMutex
 	healthStatus HealthStatus
+
+	checksMu sync.RWMutex
+	checks   map[string]HealthCheck
 }
 
 type Params struct {
@@ -46,6 +55,7 @@ func Module(scope string) fx.Option {
 				scope:        scope,
 				isReady:      false,
 				healthStatus: HealthStatus_Healthy,
+				checks:       make(map[string]HealthCheck),
 			}
 
 			return d
@@ -91,5 +101,76 @@ func (d *Daemon) Ready() bool {
 }
 
 func (d *Daemon) GetHealthStatus() HealthStatus {
+
+	d.healthMu.RLock()
+	defer d.healthMu.RUnlock()
+
 	return d.healthStatus
 }
+
+func (d *Daemon) SetHealthStatus(status HealthStatus) {
+
+	d.healthMu.Lock()
+	defer d.healthMu.Unlock()
+
+	d.healthStatus = status
+}
+
+// MarkUnhealthy sets health status to unhealthy, e.g. when background worker
+// runs into fatal condition, so load balancer stops routing traffic to it.
+func (d *Daemon) MarkUnhealthy(reason string) {
+
+	logger.Warn("Marked as unhealthy", zap.String("reason", reason))
+
+	d.SetHealthStatus(HealthStatus_Unhealthy)
+}
+
+func (d *Daemon) MarkHealthy() {
+
+	logger.Info("Marked as healthy")
+
+	d.SetHealthStatus(HealthStatus_Healthy)
+}
+
+// RegisterCheck adds named check which is run by EvaluateHealth. Registering
+// same name again replaces previous check.
+func (d *Daemon) RegisterCheck(name string, check HealthCheck) {
+
+	d.checksMu.Lock()
+	defer d.checksMu.Unlock()
+
+	d.checks[name] = check
+}
+
+// EvaluateHealth runs all registered checks and returns unhealthy if any of
+// them fails or health status was set to unhealthy.
+func (d *Daemon) EvaluateHealth(ctx context.Context) HealthStatus {
+
+	status := d.GetHealthStatus()
+
+	d.checksMu.RLock()
+	names := make([]string, 0, len(d.checks))
+	for name := range d.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	checks := make([]HealthCheck, len(names))
+	for i, name := range names {
+		checks[i] = d.checks[name]
+	}
+	d.checksMu.RUnlock()
+
+	for i, check := range checks {
+		if err := check(ctx); err != nil {
+			logger.Warn("Health check failed",
+				zap.String("check", names[i]),
+				zap.Error(err),
+			)
+
+			status = HealthStatus_Unhealthy
+		}
+	}
+
+	return status
+}
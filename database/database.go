@@ -1,9 +1,12 @@
 package database
 
 import (
+	"context"
+
 	"gorm.io/gorm"
 )
 
 type DatabaseConnector interface {
 	GetDB() *gorm.DB
+	Ping(ctx context.Context) error
 }
@@ -3,9 +3,11 @@ package healthcheck_apis
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/weedbox/common-modules/daemon"
+	"github.com/weedbox/common-modules/database"
 	"github.com/weedbox/common-modules/http_server"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
@@ -24,8 +26,11 @@ type Params struct {
 	Logger     *zap.Logger
 	HTTPServer *http_server.HTTPServer
 	Daemon     *daemon.Daemon
+	Database   database.DatabaseConnector `optional:"true"`
 }
 
+const DefaultPingTimeout = 3 * time.Second
+
 func Module(scope string) fx.Option {
 
 	var a *APIs
@@ -76,7 +81,10 @@ func (a *APIs) onStop(ctx context.Context) error {
 
 func (a *APIs) healthz(c *gin.Context) {
 
-	if a.params.Daemon.GetHealthStatus() != daemon.HealthStatus_Healthy {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), DefaultPingTimeout)
+	defer cancel()
+
+	if a.params.Daemon.EvaluateHealth(ctx) != daemon.HealthStatus_Healthy {
 
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status": "unhealthy",
@@ -92,7 +100,7 @@ func (a *APIs) healthz(c *gin.Context) {
 
 func (a *APIs) ready(c *gin.Context) {
 
-	if !a.params.Daemon.Ready() {
+	if !a.params.Daemon.Ready() || !a.databaseReady(c.Request.Context()) {
 
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"ready": false,
@@ -105,3 +113,20 @@ func (a *APIs) ready(c *gin.Context) {
 		"ready": true,
 	})
 }
+
+func (a *APIs) databaseReady(ctx context.Context) bool {
+
+	if a.params.Database == nil {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultPingTimeout)
+	defer cancel()
+
+	if err := a.params.Database.Ping(ctx); err != nil {
+		a.logger.Warn("Database is not reachable", zap.Error(err))
+		return false
+	}
+
+	return true
+}
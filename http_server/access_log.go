@@ -0,0 +1,60 @@
+package http_server
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// AccessLogger returns a middleware which writes an access log entry with
+// zap logger for every request, except for the paths in skipPaths.
+func AccessLogger(l *zap.Logger, skipPaths []string) gin.HandlerFunc {
+
+	skip := make(map[string]struct{}, len(skipPaths))
+	for _, p := range skipPaths {
+		skip[p] = struct{}{}
+	}
+
+	return func(c *gin.Context) {
+
+		start := time.Now()
+		path := c.Request.URL.Path
+
+		c.Next()
+
+		if _, ok := skip[path]; ok {
+			return
+		}
+
+		requestID := c.GetHeader(RequestIDHeader)
+		if len(requestID) == 0 {
+			requestID = c.Writer.Header().Get(RequestIDHeader)
+		}
+
+		fields := []zap.Field{
+			zap.String("method", c.Request.Method),
+			zap.String("path", path),
+			zap.Int("status", c.Writer.Status()),
+			zap.Duration("latency", time.Since(start)),
+			zap.String("client_ip", c.ClientIP()),
+			zap.String("request_id", requestID),
+		}
+
+		if len(c.Errors) > 0 {
+			fields = append(fields, zap.String("errors", c.Errors.ByType(gin.ErrorTypePrivate).String()))
+		}
+
+		status := c.Writer.Status()
+		switch {
+		case status >= 500:
+			l.Error("HTTP request", fields...)
+		case status >= 400:
+			l.Warn("HTTP request", fields...)
+		default:
+			l.Info("HTTP request", fields...)
+		}
+	}
+}
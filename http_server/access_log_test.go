@@ -0,0 +1,38 @@
+package http_server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestAccessLoggerLogsPanics(t *testing.T) {
+
+	core, logs := observer.New(zap.InfoLevel)
+
+	router := newRouter("test", AccessLogger(zap.New(core), nil))
+	router.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", w.Code)
+	}
+
+	entries := logs.FilterMessage("HTTP request").All()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 access log entry, got %d", len(entries))
+	}
+
+	if entries[0].Level != zapcore.ErrorLevel || entries[0].ContextMap()["status"] != int64(http.StatusInternalServerError) {
+		t.Errorf("unexpected access log entry %v", entries[0].ContextMap())
+	}
+}
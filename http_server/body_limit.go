@@ -0,0 +1,79 @@
+package http_server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BodySizeLimiter returns a middleware which limits the size of request body
+// to maxSize bytes and responds with 413 if it is exceeded. Zero or negative
+// value means unlimited.
+//
+// Requests without Content-Length (e.g. chunked) are detected while the body
+// is read, so error status written by handler afterwards (e.g. 400 from
+// c.BindJSON) is replaced with 413.
+func BodySizeLimiter(maxSize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		if maxSize <= 0 {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > maxSize {
+			c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		body := &limitedBody{
+			ReadCloser: http.MaxBytesReader(c.Writer, c.Request.Body, maxSize),
+		}
+
+		c.Request.Body = body
+		c.Writer = &limitedBodyWriter{
+			ResponseWriter: c.Writer,
+			body:           body,
+		}
+
+		c.Next()
+
+		// Handler failed on reading body but didn't respond
+		if body.exceeded && !c.Writer.Written() {
+			c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+		}
+	}
+}
+
+type limitedBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+
+	n, err := b.ReadCloser.Read(p)
+
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		b.exceeded = true
+	}
+
+	return n, err
+}
+
+type limitedBodyWriter struct {
+	gin.ResponseWriter
+	body *limitedBody
+}
+
+func (w *limitedBodyWriter) WriteHeader(code int) {
+
+	if w.body.exceeded && code >= http.StatusBadRequest {
+		code = http.StatusRequestEntityTooLarge
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,71 @@
+package http_server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newBodyLimitRouter(maxSize int64) *gin.Engine {
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(BodySizeLimiter(maxSize))
+	router.POST("/", func(c *gin.Context) {
+
+		var body map[string]interface{}
+		if err := c.BindJSON(&body); err != nil {
+			return
+		}
+
+		c.Status(http.StatusOK)
+	})
+
+	return router
+}
+
+// chunkedBody hides its length so request is sent without Content-Length
+type chunkedBody struct {
+	io.Reader
+}
+
+func TestBodySizeLimiter(t *testing.T) {
+
+	router := newBodyLimitRouter(16)
+
+	small := `{"a":"b"}`
+	large := `{"a":"` + strings.Repeat("x", 64) + `"}`
+
+	cases := []struct {
+		name    string
+		body    io.Reader
+		chunked bool
+		status  int
+	}{
+		{"small", strings.NewReader(small), false, http.StatusOK},
+		{"large", strings.NewReader(large), false, http.StatusRequestEntityTooLarge},
+		{"small chunked", chunkedBody{strings.NewReader(small)}, true, http.StatusOK},
+		{"large chunked", chunkedBody{strings.NewReader(large)}, true, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tc := range cases {
+
+		req := httptest.NewRequest(http.MethodPost, "/", tc.body)
+		if tc.chunked {
+			req.ContentLength = -1
+			req.TransferEncoding = []string{"chunked"}
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.status, w.Code)
+		}
+	}
+}
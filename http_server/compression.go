@@ -0,0 +1,221 @@
+package http_server
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+var DefaultCompressionContentTypes = []string{
+	"text/html",
+	"text/css",
+	"text/plain",
+	"text/javascript",
+	"application/javascript",
+	"application/json",
+	"application/xml",
+	"image/svg+xml",
+}
+
+type CompressionConfig struct {
+	// Responses shorter than MinLength bytes are sent uncompressed
+	MinLength int
+
+	// Media types which are allowed to be compressed
+	ContentTypes []string
+
+	// gzip compression level
+	Level int
+}
+
+// Compressor returns a middleware which compresses responses with gzip if
+// client accepts it. Responses which already have Content-Encoding set (e.g.
+// precompressed assets) are never compressed again.
+func Compressor(config CompressionConfig) gin.HandlerFunc {
+
+	types := make(map[string]struct{}, len(config.ContentTypes))
+	for _, t := range config.ContentTypes {
+		types[strings.ToLower(strings.TrimSpace(t))] = struct{}{}
+	}
+
+	pool := sync.Pool{
+		New: func() interface{} {
+			gz, err := gzip.NewWriterLevel(nil, config.Level)
+			if err != nil {
+				gz = gzip.NewWriter(nil)
+			}
+			return gz
+		},
+	}
+
+	return func(c *gin.Context) {
+
+		if c.Request.Method == http.MethodHead ||
+			!strings.Contains(c.GetHeader("Accept-Encoding"), "gzip") ||
+			strings.Contains(strings.ToLower(c.GetHeader("Connection")), "upgrade") {
+			c.Next()
+			return
+		}
+
+		c.Header("Vary", "Accept-Encoding")
+
+		w := &gzipWriter{
+			ResponseWriter: c.Writer,
+			minLength:      config.MinLength,
+			types:          types,
+			pool:           &pool,
+		}
+
+		c.Writer = w
+		defer w.close()
+
+		c.Next()
+	}
+}
+
+type gzipWriter struct {
+	gin.ResponseWriter
+	minLength int
+	types     map[string]struct{}
+	pool      *sync.Pool
+
+	buf      []byte
+	decided  bool
+	compress bool
+	gz       *gzip.Writer
+}
+
+func (w *gzipWriter) Write(data []byte) (int, error) {
+
+	if w.decided {
+		return w.write(data)
+	}
+
+	w.buf = append(w.buf, data...)
+	if len(w.buf) < w.minLength {
+		return len(data), nil
+	}
+
+	if err := w.decide(); err != nil {
+		return 0, err
+	}
+
+	return len(data), nil
+}
+
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *gzipWriter) WriteHeaderNow() {
+
+	// Headers are going to be sent without body
+	if !w.decided && len(w.buf) == 0 {
+		w.decided = true
+	}
+
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *gzipWriter) Written() bool {
+	return w.ResponseWriter.Written() || len(w.buf) > 0
+}
+
+func (w *gzipWriter) Flush() {
+
+	if !w.decided {
+		w.decide()
+	}
+
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+
+	w.ResponseWriter.Flush()
+}
+
+func (w *gzipWriter) write(data []byte) (int, error) {
+
+	if w.compress {
+		return w.gz.Write(data)
+	}
+
+	return w.ResponseWriter.Write(data)
+}
+
+// decide determines whether response should be compressed, then writes
+// headers and buffered data.
+func (w *gzipWriter) decide() error {
+
+	w.decided = true
+	w.compress = len(w.buf) >= w.minLength && w.compressible()
+
+	if w.compress {
+		h := w.Header()
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+
+		w.gz = w.pool.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+
+	buf := w.buf
+	w.buf = nil
+
+	if len(buf) == 0 {
+		return nil
+	}
+
+	_, err := w.write(buf)
+
+	return err
+}
+
+func (w *gzipWriter) compressible() bool {
+
+	h := w.Header()
+
+	if len(h.Get("Content-Encoding")) > 0 {
+		return false
+	}
+
+	// Byte ranges refer to uncompressed content
+	if len(h.Get("Content-Range")) > 0 {
+		return false
+	}
+
+	switch status := w.Status(); {
+	case status < http.StatusOK,
+		status == http.StatusNoContent,
+		status == http.StatusPartialContent,
+		status == http.StatusNotModified:
+		return false
+	}
+
+	contentType := h.Get("Content-Type")
+	if len(contentType) == 0 {
+		contentType = http.DetectContentType(w.buf)
+	}
+
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	_, ok := w.types[mediaType]
+
+	return ok
+}
+
+func (w *gzipWriter) close() {
+
+	if !w.decided {
+		w.decide()
+	}
+
+	if w.gz != nil {
+		w.gz.Close()
+		w.gz.Reset(nil)
+		w.pool.Put(w.gz)
+		w.gz = nil
+	}
+}
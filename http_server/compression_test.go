@@ -0,0 +1,60 @@
+package http_server
+
+import (
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCompressorSkipsPartialContent(t *testing.T) {
+
+	gin.SetMode(gin.TestMode)
+
+	content := strings.Repeat("hello world\n", 1024)
+
+	router := gin.New()
+	router.Use(Compressor(CompressionConfig{
+		MinLength:    DefaultCompressionMinLength,
+		ContentTypes: DefaultCompressionContentTypes,
+		Level:        gzip.DefaultCompression,
+	}))
+	router.GET("/file.txt", func(c *gin.Context) {
+		http.ServeContent(c.Writer, c.Request, "file.txt", time.Time{}, strings.NewReader(content))
+	})
+
+	// Full content
+	req := httptest.NewRequest(http.MethodGet, "/file.txt", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "gzip" {
+		t.Errorf("expected compressed full response, got %d %q", w.Code, w.Header().Get("Content-Encoding"))
+	}
+
+	// Byte range
+	req = httptest.NewRequest(http.MethodGet, "/file.txt", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Range", "bytes=0-4095")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("expected status 206, got %d", w.Code)
+	}
+
+	if enc := w.Header().Get("Content-Encoding"); len(enc) > 0 {
+		t.Errorf("expected partial content not to be compressed, got %q", enc)
+	}
+
+	if w.Body.String() != content[:4096] {
+		t.Error("unexpected partial content")
+	}
+}
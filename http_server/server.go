@@ -1,11 +1,16 @@
 package http_server
 
 import (
+	"compress/gzip"
 	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
-	"time"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -15,17 +20,40 @@ import (
 )
 
 const (
-	DefaultHost = "0.0.0.0"
-	DefaultPort = 80
+	DefaultHost       = "0.0.0.0"
+	DefaultPort       = 80
+	DefaultTLSEnabled = false
+
+	DefaultShutdownTimeout = 5 * time.Second
+	DefaultAccessLog       = true
+	DefaultMaxBodySize     = 10 << 20 // 10 MiB
+	DefaultUnixSocketMode  = 0660
+
+	DefaultCompressionEnabled   = false
+	DefaultCompressionMinLength = 1024
+	DefaultCompressionLevel     = gzip.DefaultCompression
 )
 
+// DefaultTrustedProxies trusts no proxy, so client IP is always taken from
+// the remote address and X-Forwarded-For/X-Real-IP headers, which can be
+// spoofed by clients, are ignored. Only list addresses or CIDRs of proxies
+// which are under your control, such as the load balancer in front of the
+// service.
+var DefaultTrustedProxies = []string{}
+
+var DefaultAccessLogSkipPaths = []string{
+	"/healthz",
+	"/ready",
+}
+
 var logger *zap.Logger
 
 type HTTPServer struct {
-	logger *zap.Logger
-	server *http.Server
-	router *gin.Engine
-	scope  string
+	logger     *zap.Logger
+	server     *http.Server
+	router     *gin.Engine
+	scope      string
+	unixSocket string
 }
 
 type Params struct {
@@ -74,6 +102,17 @@ func (hs *HTTPServer) getConfigPath(key string) string {
 func (hs *HTTPServer) initDefaultConfigs() {
 	viper.SetDefault(hs.getConfigPath("host"), DefaultHost)
 	viper.SetDefault(hs.getConfigPath("port"), DefaultPort)
+	viper.SetDefault(hs.getConfigPath("tls.enabled"), DefaultTLSEnabled)
+	viper.SetDefault(hs.getConfigPath("shutdown_timeout"), DefaultShutdownTimeout)
+	viper.SetDefault(hs.getConfigPath("access_log"), DefaultAccessLog)
+	viper.SetDefault(hs.getConfigPath("access_log_skip_paths"), DefaultAccessLogSkipPaths)
+	viper.SetDefault(hs.getConfigPath("max_body_size"), DefaultMaxBodySize)
+	viper.SetDefault(hs.getConfigPath("unix_socket_mode"), DefaultUnixSocketMode)
+	viper.SetDefault(hs.getConfigPath("trusted_proxies"), DefaultTrustedProxies)
+	viper.SetDefault(hs.getConfigPath("compression.enabled"), DefaultCompressionEnabled)
+	viper.SetDefault(hs.getConfigPath("compression.min_length"), DefaultCompressionMinLength)
+	viper.SetDefault(hs.getConfigPath("compression.content_types"), DefaultCompressionContentTypes)
+	viper.SetDefault(hs.getConfigPath("compression.level"), DefaultCompressionLevel)
 }
 
 func (hs *HTTPServer) onStart(ctx context.Context) error {
@@ -84,29 +123,118 @@ func (hs *HTTPServer) onStart(ctx context.Context) error {
 
 	logLevel := viper.GetString(hs.getConfigPath("loglevel"))
 
-	allowOrigins := viper.GetString(hs.getConfigPath("allow_origins"))
-	allowMethods := viper.GetString(hs.getConfigPath("allow_methods"))
-	allowHeaders := viper.GetString(hs.getConfigPath("allow_headers"))
+	tlsEnabled := viper.GetBool(hs.getConfigPath("tls.enabled"))
+	tlsCertFile := viper.GetString(hs.getConfigPath("tls.cert_file"))
+	tlsKeyFile := viper.GetString(hs.getConfigPath("tls.key_file"))
+
+	unixSocket := viper.GetString(hs.getConfigPath("unix_socket"))
+	if len(unixSocket) > 0 {
+		addr = unixSocket
+	}
 
 	logger.Info("Starting HTTPServer",
 		zap.String("address", addr),
+		zap.Bool("tls", tlsEnabled),
 	)
 
-	if logLevel == "test" {
-		gin.SetMode(gin.TestMode)
+	var accessLogger gin.HandlerFunc
+	if viper.GetBool(hs.getConfigPath("access_log")) {
+		accessLogger = AccessLogger(logger, viper.GetStringSlice(hs.getConfigPath("access_log_skip_paths")))
 	}
 
-	if logLevel == "release" || logLevel == "prod" {
-		gin.SetMode(gin.ReleaseMode)
+	hs.router = newRouter(logLevel, accessLogger)
+
+	trustedProxies := viper.GetStringSlice(hs.getConfigPath("trusted_proxies"))
+	if len(trustedProxies) == 0 {
+		trustedProxies = nil
+	}
+
+	if err := hs.router.SetTrustedProxies(trustedProxies); err != nil {
+		return fmt.Errorf("http_server: invalid trusted proxies: %w", err)
 	}
 
-	hs.router = gin.Default()
+	hs.router.Use(BodySizeLimiter(viper.GetInt64(hs.getConfigPath("max_body_size"))))
 
-	if logLevel == "prod" {
-		hs.router = gin.New()
+	if viper.GetBool(hs.getConfigPath("compression.enabled")) {
+		hs.router.Use(Compressor(CompressionConfig{
+			MinLength:    viper.GetInt(hs.getConfigPath("compression.min_length")),
+			ContentTypes: viper.GetStringSlice(hs.getConfigPath("compression.content_types")),
+			Level:        viper.GetInt(hs.getConfigPath("compression.level")),
+		}))
 	}
 
 	// Setup Cors
+	corsConfig, err := hs.buildCorsConfig()
+	if err != nil {
+		return err
+	}
+	hs.router.Use(cors.New(corsConfig))
+
+	hs.server = &http.Server{
+		Addr:    addr,
+		Handler: hs.router,
+	}
+
+	if tlsEnabled {
+
+		// Load certificate before listening so invalid files fail startup
+		cert, err := tls.LoadX509KeyPair(tlsCertFile, tlsKeyFile)
+		if err != nil {
+			return fmt.Errorf("http_server: failed to load TLS certificate: %w", err)
+		}
+
+		hs.server.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+		}
+	}
+
+	var listener net.Listener
+	if len(unixSocket) > 0 {
+		l, err := hs.listenUnix(unixSocket, os.FileMode(viper.GetUint32(hs.getConfigPath("unix_socket_mode"))))
+		if err != nil {
+			return err
+		}
+
+		listener = l
+	} else {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+
+		listener = l
+	}
+
+	go func() {
+
+		var err error
+		if tlsEnabled {
+			err = hs.server.ServeTLS(listener, "", "")
+		} else {
+			err = hs.server.Serve(listener)
+		}
+
+		if err != nil && err != http.ErrServerClosed {
+			logger.Fatal(err.Error())
+		}
+	}()
+
+	return nil
+}
+
+func (hs *HTTPServer) buildCorsConfig() (cors.Config, error) {
+
+	allowOrigins := viper.GetString(hs.getConfigPath("allow_origins"))
+	allowMethods := viper.GetString(hs.getConfigPath("allow_methods"))
+	allowHeaders := viper.GetString(hs.getConfigPath("allow_headers"))
+	allowCredentials := viper.GetBool(hs.getConfigPath("allow_credentials"))
+	exposeHeaders := viper.GetString(hs.getConfigPath("expose_headers"))
+
+	maxAge, err := parseMaxAge(viper.GetString(hs.getConfigPath("max_age")))
+	if err != nil {
+		return cors.Config{}, err
+	}
+
 	corsConfig := cors.DefaultConfig()
 
 	if allowOrigins != "" {
@@ -129,28 +257,171 @@ func (hs *HTTPServer) onStart(ctx context.Context) error {
 			corsConfig.AllowHeaders = append(corsConfig.AllowHeaders, a)
 		}
 	}
-	hs.router.Use(cors.New(corsConfig))
+	if exposeHeaders != "" {
+		corsConfig.ExposeHeaders = strings.Split(exposeHeaders, ",")
+	}
+	if maxAge > 0 {
+		corsConfig.MaxAge = maxAge
+	}
+	if allowCredentials {
 
-	hs.server = &http.Server{
-		Addr:    addr,
-		Handler: hs.router,
+		// Browsers reject credentials with wildcard origin
+		if corsConfig.AllowAllOrigins || containsWildcardOrigin(corsConfig.AllowOrigins) {
+			return corsConfig, fmt.Errorf("http_server: allow_credentials cannot be used with wildcard origin, list allowed origins in allow_origins")
+		}
+
+		corsConfig.AllowCredentials = true
+	}
+	if err := corsConfig.Validate(); err != nil {
+		return corsConfig, fmt.Errorf("http_server: invalid CORS configuration: %w", err)
 	}
 
-	go func() {
-		if err := hs.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Fatal(err.Error())
+	return corsConfig, nil
+}
+
+// parseMaxAge parses max_age, which is either a duration (e.g. "10m") or an
+// integer number of seconds as Access-Control-Max-Age is.
+func parseMaxAge(v string) (time.Duration, error) {
+
+	v = strings.TrimSpace(v)
+	if len(v) == 0 {
+		return 0, nil
+	}
+
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("http_server: invalid max_age \"%s\", use seconds or duration such as \"10m\"", v)
+	}
+
+	return d, nil
+}
+
+func containsWildcardOrigin(origins []string) bool {
+
+	for _, origin := range origins {
+		if strings.TrimSpace(origin) == "*" {
+			return true
 		}
-	}()
+	}
 
-	return nil
+	return false
+}
+
+func (hs *HTTPServer) listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(path, mode); err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	hs.unixSocket = path
+
+	return l, nil
+}
+
+// removeStaleSocket removes socket file left by previous process. It refuses
+// to remove anything else than a socket, or a socket which is still served.
+func removeStaleSocket(path string) error {
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("http_server: %s exists and is not a socket", path)
+	}
+
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("http_server: socket %s is in use by another process", path)
+	}
+
+	return os.Remove(path)
+}
+
+// newRouter creates gin engine with recovery middleware for specific log level:
+//
+//	debug:   debug mode
+//	test:    test mode
+//	release: release mode
+//	prod:    release mode
+//
+// gin mode is left untouched (GIN_MODE) if log level is not specified. Access
+// logs are written by AccessLogger instead of gin's text logger.
+// newRouter creates router with recovery middleware. Access logger is
+// installed before recovery, so requests which panicked are logged as well.
+func newRouter(logLevel string, accessLogger gin.HandlerFunc) *gin.Engine {
+
+	switch logLevel {
+	case "debug":
+		gin.SetMode(gin.DebugMode)
+	case "test":
+		gin.SetMode(gin.TestMode)
+	case "release", "prod":
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	router := gin.New()
+
+	if accessLogger != nil {
+		router.Use(accessLogger)
+	}
+
+	router.Use(gin.Recovery())
+
+	return router
 }
 
 func (hs *HTTPServer) onStop(ctx context.Context) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	if hs.server == nil {
+		return nil
+	}
+
+	timeout := viper.GetDuration(hs.getConfigPath("shutdown_timeout"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	hs.server.Shutdown(ctx)
 
-	logger.Info("Stopped HTTPServer")
+	start := time.Now()
+	if err := hs.server.Shutdown(ctx); err != nil {
+		logger.Warn("HTTPServer was not shut down gracefully",
+			zap.Duration("timeout", timeout),
+			zap.Error(err),
+		)
+	}
+
+	if len(hs.unixSocket) > 0 {
+		if err := os.Remove(hs.unixSocket); err != nil && !os.IsNotExist(err) {
+			logger.Warn("Failed to remove unix socket",
+				zap.String("path", hs.unixSocket),
+				zap.Error(err),
+			)
+		}
+	}
+
+	logger.Info("Stopped HTTPServer",
+		zap.Duration("elapsed", time.Since(start)),
+	)
 
 	return nil
 }
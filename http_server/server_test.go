@@ -0,0 +1,92 @@
+package http_server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+func TestNewRouterMode(t *testing.T) {
+
+	defer gin.SetMode(gin.TestMode)
+
+	cases := map[string]string{
+		"debug":   gin.DebugMode,
+		"test":    gin.TestMode,
+		"release": gin.ReleaseMode,
+		"prod":    gin.ReleaseMode,
+	}
+
+	for level, mode := range cases {
+
+		// Start from a different mode to make sure it is changed
+		gin.SetMode(gin.DebugMode)
+		if mode == gin.DebugMode {
+			gin.SetMode(gin.ReleaseMode)
+		}
+
+		newRouter(level, nil)
+
+		if gin.Mode() != mode {
+			t.Errorf("expected mode %q for %q, got %q", mode, level, gin.Mode())
+		}
+	}
+}
+
+func TestCorsCredentialsWithWildcardOrigin(t *testing.T) {
+
+	cases := map[string]bool{
+		"":                                false,
+		"*":                               false,
+		"https://a.example.com, *":        false,
+		"https://a.example.com":           true,
+		"https://a.example.com,https://b": true,
+	}
+
+	for origins, valid := range cases {
+
+		hs := &HTTPServer{scope: "http_cors"}
+		hs.initDefaultConfigs()
+
+		viper.Set(hs.getConfigPath("allow_origins"), origins)
+		viper.Set(hs.getConfigPath("allow_credentials"), true)
+
+		_, err := hs.buildCorsConfig()
+		if valid && err != nil {
+			t.Errorf("expected %q to be accepted: %v", origins, err)
+		}
+
+		if !valid && err == nil {
+			t.Errorf("expected credentials with origins %q to be rejected", origins)
+		}
+	}
+}
+
+func TestParseMaxAge(t *testing.T) {
+
+	cases := map[string]time.Duration{
+		"":    0,
+		"600": 600 * time.Second,
+		"10m": 10 * time.Minute,
+		"1h":  time.Hour,
+	}
+
+	for v, expected := range cases {
+
+		d, err := parseMaxAge(v)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", v, err)
+			continue
+		}
+
+		if d != expected {
+			t.Errorf("expected %s for %q, got %s", expected, v, d)
+		}
+	}
+
+	if _, err := parseMaxAge("ten minutes"); err == nil {
+		t.Error("expected invalid max_age to be rejected")
+	}
+}
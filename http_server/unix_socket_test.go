@@ -0,0 +1,63 @@
+//go:build !windows
+
+package http_server
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRemoveStaleSocket(t *testing.T) {
+
+	dir := t.TempDir()
+
+	// Regular file
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := removeStaleSocket(file); err == nil {
+		t.Error("expected regular file to be refused")
+	}
+
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("expected regular file to be kept: %v", err)
+	}
+
+	// Socket which is still served
+	live := filepath.Join(dir, "live.sock")
+	l, err := net.Listen("unix", live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if err := removeStaleSocket(live); err == nil {
+		t.Error("expected socket in use to be refused")
+	}
+
+	// Stale socket
+	stale := filepath.Join(dir, "stale.sock")
+	sl, err := net.Listen("unix", stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sl.(*net.UnixListener).SetUnlinkOnClose(false)
+	sl.Close()
+
+	if err := removeStaleSocket(stale); err != nil {
+		t.Fatalf("expected stale socket to be removed: %v", err)
+	}
+
+	if _, err := os.Lstat(stale); !os.IsNotExist(err) {
+		t.Error("expected stale socket to be removed")
+	}
+
+	// Missing
+	if err := removeStaleSocket(filepath.Join(dir, "missing.sock")); err != nil {
+		t.Errorf("unexpected error for missing socket: %v", err)
+	}
+}
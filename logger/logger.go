@@ -2,14 +2,21 @@ package logger
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 
+	"github.com/spf13/viper"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+// Settings can be configured under this scope, while DEBUG_MODE, DEBUG_LEVEL
+// and LOG_FORMAT environment variables take precedence over them.
+const ConfigScope = "logger"
+
 var logger *zap.Logger
+var level = zap.NewAtomicLevel()
 
 type Params struct {
 	fx.In
@@ -38,17 +45,28 @@ func NewCustomEncoderConfig() zapcore.EncoderConfig {
 	}
 }
 
+func NewJSONEncoderConfig() zapcore.EncoderConfig {
+	config := NewCustomEncoderConfig()
+	config.EncodeLevel = zapcore.LowercaseLevelEncoder
+	config.EncodeTime = zapcore.RFC3339TimeEncoder
+	return config
+}
+
 func SetupLogger() *zap.Logger {
 	debugLevel := setupLevel()
+	level = debugLevel
 	core := zapcore.NewCore(
-		zapcore.NewConsoleEncoder(NewCustomEncoderConfig()),
+		setupEncoder(),
 		zapcore.NewMultiWriteSyncer(zapcore.AddSync(os.Stdout)),
 		debugLevel,
 	)
 
-	if os.Getenv("DEBUG_MODE") == "debug" {
-		logger.Info(fmt.Sprintf("Debug mode is set to \"%s\"\n", debugLevel.String()))
+	// Values of these fields are replaced with "***"
+	core = newRedactCore(core, viper.GetStringSlice(getConfigPath("redact_keys")))
+
+	if isDevelopment() {
 		logger = zap.New(core, zap.AddCaller(), zap.Development())
+		logger.Info(fmt.Sprintf("Debug mode is set to \"%s\"\n", debugLevel.String()))
 	} else {
 		logger = zap.New(core)
 	}
@@ -64,10 +82,66 @@ func GetLogger() *zap.Logger {
 	return logger
 }
 
+// SetLevel changes level of the logger at runtime.
+func SetLevel(l zapcore.Level) {
+	level.SetLevel(l)
+}
+
+func GetLevel() zapcore.Level {
+	return level.Level()
+}
+
+// LevelHandler returns HTTP handler which reports current level with GET and
+// changes it with PUT, e.g. {"level":"debug"}.
+func LevelHandler() http.Handler {
+	return level
+}
+
+func getConfigPath(key string) string {
+	return fmt.Sprintf("%s.%s", ConfigScope, key)
+}
+
+// getSetting returns value of environment variable if it is set, otherwise
+// value of config key.
+func getSetting(env string, key string) string {
+
+	if v, ok := os.LookupEnv(env); ok {
+		return v
+	}
+
+	return viper.GetString(getConfigPath(key))
+}
+
+func isDevelopment() bool {
+
+	if v, ok := os.LookupEnv("DEBUG_MODE"); ok {
+		return v == "debug"
+	}
+
+	return viper.GetBool(getConfigPath("development"))
+}
+
+func setupEncoder() zapcore.Encoder {
+
+	format := getSetting("LOG_FORMAT", "format")
+	if format == "" {
+		format = "json"
+		if isDevelopment() {
+			format = "console"
+		}
+	}
+
+	if format == "json" {
+		return zapcore.NewJSONEncoder(NewJSONEncoderConfig())
+	}
+
+	return zapcore.NewConsoleEncoder(NewCustomEncoderConfig())
+}
+
 func setupLevel() zap.AtomicLevel {
 
 	debugLevel := zap.DebugLevel
-	switch os.Getenv("DEBUG_LEVEL") {
+	switch getSetting("DEBUG_LEVEL", "level") {
 	case zap.InfoLevel.String():
 		debugLevel = zap.InfoLevel
 	case zap.WarnLevel.String():
@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const RedactedValue = "***"
+
+// redactCore replaces values of fields whose keys are in the redaction list,
+// so sensitive data never reaches the encoder.
+type redactCore struct {
+	zapcore.Core
+	keys map[string]struct{}
+}
+
+func newRedactCore(core zapcore.Core, keys []string) zapcore.Core {
+
+	if len(keys) == 0 {
+		return core
+	}
+
+	rc := &redactCore{
+		Core: core,
+		keys: make(map[string]struct{}, len(keys)),
+	}
+
+	for _, key := range keys {
+		rc.keys[strings.ToLower(key)] = struct{}{}
+	}
+
+	return rc
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{
+		Core: c.Core.With(c.redact(fields)),
+		keys: c.keys,
+	}
+}
+
+func (c *redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+
+	return ce
+}
+
+func (c *redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, c.redact(fields))
+}
+
+func (c *redactCore) redact(fields []zapcore.Field) []zapcore.Field {
+
+	var redacted []zapcore.Field
+
+	for i, f := range fields {
+
+		if _, ok := c.keys[strings.ToLower(f.Key)]; !ok || f.Type == zapcore.NamespaceType {
+			continue
+		}
+
+		// Copy on first match to leave caller's slice untouched
+		if redacted == nil {
+			redacted = make([]zapcore.Field, len(fields))
+			copy(redacted, fields)
+		}
+
+		redacted[i] = zap.String(f.Key, RedactedValue)
+	}
+
+	if redacted == nil {
+		return fields
+	}
+
+	return redacted
+}
@@ -0,0 +1,28 @@
+package logger
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// WithContext returns child logger with trace_id and span_id of the active
+// OpenTelemetry span in ctx, or the logger itself if there is no valid span.
+func WithContext(ctx context.Context) *zap.Logger {
+
+	l := GetLogger()
+	if l == nil {
+		l = zap.L()
+	}
+
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if !spanCtx.IsValid() {
+		return l
+	}
+
+	return l.With(
+		zap.String("trace_id", spanCtx.TraceID().String()),
+		zap.String("span_id", spanCtx.SpanID().String()),
+	)
+}
@@ -0,0 +1,74 @@
+package loglevel_apis
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weedbox/common-modules/http_server"
+	"github.com/weedbox/common-modules/logger"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type APIs struct {
+	params Params
+	logger *zap.Logger
+	scope  string
+}
+
+type Params struct {
+	fx.In
+
+	Lifecycle  fx.Lifecycle
+	Logger     *zap.Logger
+	HTTPServer *http_server.HTTPServer
+}
+
+func Module(scope string) fx.Option {
+
+	var a *APIs
+
+	return fx.Module(
+		scope,
+		fx.Provide(func(p Params) *APIs {
+
+			a := &APIs{
+				params: p,
+				logger: p.Logger.Named(scope),
+				scope:  scope,
+			}
+
+			return a
+		}),
+		fx.Populate(&a),
+		fx.Invoke(func(p Params) {
+
+			p.Lifecycle.Append(
+				fx.Hook{
+					OnStart: a.onStart,
+					OnStop:  a.onStop,
+				},
+			)
+		}),
+	)
+
+}
+
+func (a *APIs) onStart(ctx context.Context) error {
+
+	a.logger.Info("Starting log level APIs")
+
+	router := a.params.HTTPServer.GetRouter()
+
+	handler := gin.WrapH(logger.LevelHandler())
+	router.GET("/loglevel", handler)
+	router.PUT("/loglevel", handler)
+
+	return nil
+}
+
+func (a *APIs) onStop(ctx context.Context) error {
+	a.logger.Info("Stopped log level APIs")
+
+	return nil
+}
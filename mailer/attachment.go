@@ -0,0 +1,104 @@
+package mailer
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/gomail.v2"
+)
+
+// Attach attaches content of r to message as filename. Content is read into
+// memory immediately, so the message can be sent again on retry.
+func (m *Mailer) Attach(msg *gomail.Message, filename string, r io.Reader) error {
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	msg.Attach(filename, fileSettings(filename, data)...)
+
+	return nil
+}
+
+// Embed embeds content of r (e.g. inline image) to message, which can be
+// referenced with "cid:<cid>" in HTML body.
+func (m *Mailer) Embed(msg *gomail.Message, cid string, r io.Reader) error {
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	msg.Embed(cid, fileSettings(cid, data)...)
+
+	return nil
+}
+
+// SendWithAttachments sends message with files attached, keyed by filename.
+// HTML body is sent along with plain text alternative.
+func (m *Mailer) SendWithAttachments(to []string, subject string, body string, files map[string]io.Reader) error {
+
+	msg := m.NewMessage()
+	msg.SetHeader("From", m.from)
+	msg.SetHeader("To", to...)
+	msg.SetHeader("Subject", subject)
+
+	if strings.HasPrefix(http.DetectContentType([]byte(body)), "text/html") {
+		msg.SetBody("text/plain", htmlToText(body))
+		msg.AddAlternative("text/html", body)
+	} else {
+		msg.SetBody("text/plain", body)
+	}
+
+	// Keep order of attachments stable
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := m.Attach(msg, name, files[name]); err != nil {
+			return fmt.Errorf("mailer: failed to attach \"%s\": %w", name, err)
+		}
+	}
+
+	return m.Send(msg)
+}
+
+func fileSettings(filename string, data []byte) []gomail.FileSetting {
+
+	name := filepath.Base(filename)
+
+	return []gomail.FileSetting{
+		gomail.SetHeader(map[string][]string{
+			"Content-Type": {fmt.Sprintf(`%s; name="%s"`, detectContentType(name, data), name)},
+		}),
+		gomail.SetCopyFunc(func(w io.Writer) error {
+			_, err := w.Write(data)
+			return err
+		}),
+	}
+}
+
+// detectContentType detects media type by file extension, or by content if
+// extension is unknown.
+func detectContentType(filename string, data []byte) string {
+
+	if t := mime.TypeByExtension(filepath.Ext(filename)); len(t) > 0 {
+		mediaType, _, err := mime.ParseMediaType(t)
+		if err == nil {
+			return mediaType
+		}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+
+	return mediaType
+}
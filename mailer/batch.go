@@ -0,0 +1,108 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/gomail.v2"
+)
+
+// BatchError is returned by SendBatch with errors of the messages which
+// failed to be sent, keyed by index of message.
+type BatchError struct {
+	Errors map[int]error
+}
+
+func (e *BatchError) Error() string {
+
+	indices := make([]int, 0, len(e.Errors))
+	for i := range e.Errors {
+		indices = append(indices, i)
+	}
+
+	sort.Ints(indices)
+
+	msgs := make([]string, 0, len(indices))
+	for _, i := range indices {
+		msgs = append(msgs, fmt.Sprintf("#%d: %v", i, e.Errors[i]))
+	}
+
+	return fmt.Sprintf("mailer: failed to send %d messages: %s", len(indices), strings.Join(msgs, "; "))
+}
+
+// SendBatch sends messages over a single SMTP connection, which is much
+// faster than Send for bulk mails. Each message is sent with send timeout and
+// retried like Send. The connection is re-established if it fails in the
+// middle of the batch.
+//
+// A failed message doesn't stop the batch, *BatchError is returned with errors
+// of all failed messages.
+func (m *Mailer) SendBatch(msgs []*gomail.Message) error {
+
+	var c *smtpConn
+
+	// Closes the latest connection
+	defer func() {
+
+		if c == nil {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), m.sendTimeout)
+		defer cancel()
+
+		c.close(ctx)
+	}()
+
+	failed := make(map[int]error)
+
+	for i, msg := range msgs {
+
+		ctx, cancel := context.WithTimeout(context.Background(), m.sendTimeout)
+
+		err := m.retry(ctx, func() error {
+
+			if c == nil {
+
+				nc, err := m.dial(ctx)
+				if err != nil {
+					return err
+				}
+
+				c = nc
+			}
+
+			err := c.send(ctx, msg)
+			if err == nil {
+				return nil
+			}
+
+			// Message was rejected, connection can be reused for the rest
+			// after resetting the transaction
+			if isPermanentError(err) && c.do(ctx, c.client.Reset) == nil {
+				return err
+			}
+
+			c.conn.Close()
+			c = nil
+
+			return err
+		})
+
+		cancel()
+
+		if err != nil {
+			failed[i] = err
+		}
+	}
+
+	if len(failed) > 0 {
+		return &BatchError{
+			Errors: failed,
+		}
+	}
+
+	return nil
+}
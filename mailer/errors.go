@@ -0,0 +1,36 @@
+package mailer
+
+import (
+	"errors"
+	"net/textproto"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// gomail formats SMTP errors of sending with %v, so the reply code has to be
+// parsed from error message.
+var smtpReplyPattern = regexp.MustCompile(`^gomail: could not send email \d+: (\d{3}) `)
+
+// isPermanentError reports whether err is a permanent failure which won't
+// succeed on retry, such as 5xx SMTP replies or invalid addresses.
+func isPermanentError(err error) bool {
+
+	var tpErr *textproto.Error
+	if errors.As(err, &tpErr) {
+		return tpErr.Code >= 500
+	}
+
+	msg := err.Error()
+
+	if strings.HasPrefix(msg, "gomail: invalid address") {
+		return true
+	}
+
+	if matches := smtpReplyPattern.FindStringSubmatch(msg); matches != nil {
+		code, _ := strconv.Atoi(matches[1])
+		return code >= 500
+	}
+
+	return false
+}
@@ -3,7 +3,14 @@ package mailer
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/spf13/viper"
 	"go.uber.org/fx"
@@ -11,12 +18,34 @@ import (
 	"gopkg.in/gomail.v2"
 )
 
+const (
+	EncryptionNone     = "none"
+	EncryptionSTARTTLS = "starttls"
+	EncryptionSSL      = "ssl"
+)
+
 const (
 	DefaultHost     = "0.0.0.0"
 	DefaultPort     = 25
 	DefaultUsername = ""
 	DefaultPassword = ""
-	DefaultTLS      = false
+	DefaultFrom     = ""
+
+	// Deprecated: tls setting is ignored, server certificate is always
+	// verified unless tls.insecure_skip_verify is set.
+	DefaultTLS = false
+
+	// Skipping verification exposes the connection to man-in-the-middle
+	// attacks, only enable it for testing or trusted networks.
+	DefaultTLSInsecureSkipVerify = false
+
+	// Encryption is detected by port if not specified
+	DefaultEncryption = ""
+
+	DefaultRetries       = 3
+	DefaultRetryInterval = time.Second
+	MaxRetryInterval     = 30 * time.Second
+	DefaultSendTimeout   = time.Minute
 )
 
 var logger *zap.Logger
@@ -25,6 +54,20 @@ type Mailer struct {
 	logger *zap.Logger
 	dialer *gomail.Dialer
 	scope  string
+	from   string
+
+	encryption string
+
+	retries       int
+	retryInterval time.Duration
+	sendTimeout   time.Duration
+
+	tmplMu           sync.Mutex
+	templateDir      string
+	templateReload   bool
+	templateFS       fs.FS
+	templates        *template.Template
+	templatesModTime time.Time
 }
 
 type Params struct {
@@ -76,9 +119,15 @@ func (m *Mailer) getConfigPath(key string) string {
 func (m *Mailer) initDefaultConfigs() {
 	viper.SetDefault(m.getConfigPath("host"), DefaultHost)
 	viper.SetDefault(m.getConfigPath("port"), DefaultPort)
-	viper.SetDefault(m.getConfigPath("tls"), DefaultTLS)
+	viper.SetDefault(m.getConfigPath("tls.insecure_skip_verify"), DefaultTLSInsecureSkipVerify)
+	viper.SetDefault(m.getConfigPath("encryption"), DefaultEncryption)
 	viper.SetDefault(m.getConfigPath("username"), DefaultUsername)
 	viper.SetDefault(m.getConfigPath("password"), DefaultPassword)
+	viper.SetDefault(m.getConfigPath("from"), DefaultFrom)
+	viper.SetDefault(m.getConfigPath("retries"), DefaultRetries)
+	viper.SetDefault(m.getConfigPath("retry_interval"), DefaultRetryInterval)
+	viper.SetDefault(m.getConfigPath("send_timeout"), DefaultSendTimeout)
+	viper.SetDefault(m.getConfigPath("template_reload"), os.Getenv("DEBUG_MODE") == "debug")
 }
 
 func (m *Mailer) onStart(ctx context.Context) error {
@@ -87,21 +136,117 @@ func (m *Mailer) onStart(ctx context.Context) error {
 
 	host := viper.GetString(m.getConfigPath("host"))
 	port := viper.GetInt(m.getConfigPath("port"))
-	enabledTLS := viper.GetBool(m.getConfigPath("tls"))
 	username := viper.GetString(m.getConfigPath("username"))
 	password := viper.GetString(m.getConfigPath("password"))
 
 	m.dialer = gomail.NewDialer(host, port, username, password)
 
-	if enabledTLS {
-		m.dialer.TLSConfig = &tls.Config{
-			InsecureSkipVerify: true,
+	m.from = viper.GetString(m.getConfigPath("from"))
+	if len(m.from) == 0 {
+		m.from = username
+	}
+
+	m.templateDir = viper.GetString(m.getConfigPath("template_dir"))
+	m.templateReload = viper.GetBool(m.getConfigPath("template_reload"))
+	m.retries = viper.GetInt(m.getConfigPath("retries"))
+	m.retryInterval = viper.GetDuration(m.getConfigPath("retry_interval"))
+	m.sendTimeout = viper.GetDuration(m.getConfigPath("send_timeout"))
+
+	tlsConfig, err := m.buildTLSConfig(host)
+	if err != nil {
+		return err
+	}
+
+	m.dialer.TLSConfig = tlsConfig
+
+	return m.setupEncryption(port)
+}
+
+// setupEncryption configures implicit TLS (SSL, usually port 465) or STARTTLS
+// (usually port 587), which fails if server doesn't support it. "none" never
+// encrypts the connection. If not specified, implicit TLS is used for port 465
+// and connection is upgraded with STARTTLS if server supports it.
+func (m *Mailer) setupEncryption(port int) error {
+
+	encryption := viper.GetString(m.getConfigPath("encryption"))
+
+	switch encryption {
+	case "":
+		// gomail enables SSL for port 465
+		m.encryption = encryption
+		return nil
+	case EncryptionSSL:
+		m.dialer.SSL = true
+		if port != 465 {
+			logger.Warn("Implicit TLS is usually served on port 465", zap.Int("port", port))
+		}
+	case EncryptionSTARTTLS, EncryptionNone:
+		m.dialer.SSL = false
+		if port == 465 {
+			logger.Warn("Port 465 usually requires implicit TLS, set encryption to ssl", zap.Int("port", port))
 		}
+	default:
+		return fmt.Errorf("mailer: invalid encryption \"%s\"", encryption)
 	}
 
+	m.encryption = encryption
+
 	return nil
 }
 
+func (m *Mailer) buildTLSConfig(host string) (*tls.Config, error) {
+
+	// tls setting of previous versions disabled verification when enabled
+	if m.isLegacyTLSEnabled() {
+		logger.Warn("tls setting is no longer used and server certificate is verified now, set tls.insecure_skip_verify to skip verification")
+	}
+
+	serverName := viper.GetString(m.getConfigPath("tls.server_name"))
+	if len(serverName) == 0 {
+		serverName = host
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: viper.GetBool(m.getConfigPath("tls.insecure_skip_verify")),
+	}
+
+	if tlsConfig.InsecureSkipVerify {
+		logger.Warn("TLS certificate verification is disabled")
+	}
+
+	caFile := viper.GetString(m.getConfigPath("tls.ca_file"))
+	if len(caFile) > 0 {
+
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("mailer: failed to load CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("mailer: no valid certificate found in CA file \"%s\"", caFile)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+func (m *Mailer) isLegacyTLSEnabled() bool {
+
+	switch v := viper.Get(m.getConfigPath("tls")).(type) {
+	case bool:
+		return v
+	case string:
+		enabled, _ := strconv.ParseBool(v)
+		return enabled
+	}
+
+	return false
+}
+
 func (m *Mailer) onStop(ctx context.Context) error {
 
 	logger.Info("Stopped mailer")
@@ -113,6 +258,70 @@ func (m *Mailer) NewMessage() *gomail.Message {
 	return gomail.NewMessage()
 }
 
+// Send sends message with default timeout. See SendContext.
 func (m *Mailer) Send(msg *gomail.Message) error {
-	return m.dialer.DialAndSend(msg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.sendTimeout)
+	defer cancel()
+
+	return m.SendContext(ctx, msg)
+}
+
+// SendContext sends message and retries with exponential backoff on transient
+// failures. Permanent SMTP errors (5xx) are returned immediately. When ctx is
+// done, the connection of the attempt in progress is closed and ctx.Err() is
+// returned.
+func (m *Mailer) SendContext(ctx context.Context, msg *gomail.Message) error {
+	return m.retry(ctx, func() error {
+		return m.dialAndSend(ctx, msg)
+	})
+}
+
+// retry calls send until it succeeds, fails permanently, or retries or ctx is
+// exhausted.
+func (m *Mailer) retry(ctx context.Context, send func() error) error {
+
+	interval := m.retryInterval
+
+	for attempt := 0; ; attempt++ {
+
+		err := send()
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= m.retries || isPermanentError(err) || ctx.Err() != nil {
+			return err
+		}
+
+		logger.Warn("Failed to send mail, retrying",
+			zap.Int("attempt", attempt+1),
+			zap.Int("retries", m.retries),
+			zap.Duration("interval", interval),
+			zap.Error(err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+
+		interval *= 2
+		if interval > MaxRetryInterval {
+			interval = MaxRetryInterval
+		}
+	}
+}
+
+func (m *Mailer) dialAndSend(ctx context.Context, msg *gomail.Message) error {
+
+	c, err := m.dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer c.close(ctx)
+
+	return c.send(ctx, msg)
 }
@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLegacyTLSWarning(t *testing.T) {
+
+	cases := map[interface{}]bool{
+		true:    true,
+		"true":  true,
+		false:   false,
+		"false": false,
+	}
+
+	for value, warned := range cases {
+
+		core, logs := observer.New(zap.WarnLevel)
+		logger = zap.New(core)
+
+		m := &Mailer{scope: "mailer_legacy_tls"}
+		m.initDefaultConfigs()
+		viper.Set(m.getConfigPath("tls"), value)
+
+		if _, err := m.buildTLSConfig("localhost"); err != nil {
+			t.Fatal(err)
+		}
+
+		if (logs.Len() > 0) != warned {
+			t.Errorf("tls=%v: expected warning %v, got %d entries", value, warned, logs.Len())
+		}
+	}
+}
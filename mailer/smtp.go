@@ -0,0 +1,248 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"gopkg.in/gomail.v2"
+)
+
+const dialTimeout = 10 * time.Second
+
+// smtpConn is a SMTP connection dialed by Mailer instead of gomail, so that
+// it can be closed to interrupt a blocking operation when context is done.
+type smtpConn struct {
+	conn   net.Conn
+	client *smtp.Client
+}
+
+// dial connects and authenticates to SMTP server in the same way as
+// gomail.Dialer.Dial does, except for enforcing encryption setting.
+func (m *Mailer) dial(ctx context.Context) (*smtpConn, error) {
+
+	d := m.dialer
+
+	nd := &net.Dialer{
+		Timeout: dialTimeout,
+	}
+
+	conn, err := nd.DialContext(ctx, "tcp", fmt.Sprintf("%s:%d", d.Host, d.Port))
+	if err != nil {
+		return nil, err
+	}
+
+	c := &smtpConn{
+		conn: conn,
+	}
+
+	err = c.do(ctx, func() error {
+
+		if d.SSL {
+			conn = tls.Client(conn, d.TLSConfig)
+		}
+
+		client, err := smtp.NewClient(conn, d.Host)
+		if err != nil {
+			return err
+		}
+
+		c.client = client
+
+		if err := m.startTLS(client); err != nil {
+			return err
+		}
+
+		auth := m.auth(client)
+		if auth == nil {
+			return nil
+		}
+
+		return client.Auth(auth)
+	})
+	if err != nil {
+		c.conn.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// startTLS upgrades the connection as configured by encryption setting.
+func (m *Mailer) startTLS(client *smtp.Client) error {
+
+	if m.dialer.SSL || m.encryption == EncryptionNone {
+		return nil
+	}
+
+	ok, _ := client.Extension("STARTTLS")
+	if !ok {
+
+		if m.encryption == EncryptionSTARTTLS {
+			return errors.New("mailer: server doesn't support STARTTLS")
+		}
+
+		return nil
+	}
+
+	return client.StartTLS(m.dialer.TLSConfig)
+}
+
+// auth chooses authentication mechanism advertised by server like gomail.
+func (m *Mailer) auth(client *smtp.Client) smtp.Auth {
+
+	d := m.dialer
+
+	if len(d.Username) == 0 {
+		return nil
+	}
+
+	ok, auths := client.Extension("AUTH")
+	if !ok {
+		return nil
+	}
+
+	switch {
+	case strings.Contains(auths, "CRAM-MD5"):
+		return smtp.CRAMMD5Auth(d.Username, d.Password)
+	case strings.Contains(auths, "LOGIN") && !strings.Contains(auths, "PLAIN"):
+		return &loginAuth{
+			username: d.Username,
+			password: d.Password,
+			host:     d.Host,
+		}
+	}
+
+	return smtp.PlainAuth("", d.Username, d.Password, d.Host)
+}
+
+// do runs fn and closes the connection if ctx is done before fn returns,
+// which makes fn fail immediately instead of waiting for server.
+func (c *smtpConn) do(ctx context.Context, fn func() error) error {
+
+	if err := ctx.Err(); err != nil {
+		c.conn.Close()
+		return err
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		select {
+		case <-ctx.Done():
+			c.conn.Close()
+		case <-done:
+		}
+	}()
+
+	err := fn()
+
+	close(done)
+	<-stopped
+
+	// Error is caused by closing the connection
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return err
+}
+
+// send sends message over the connection. Connection is closed if ctx is done
+// before message is sent.
+func (c *smtpConn) send(ctx context.Context, msg *gomail.Message) error {
+	return c.do(ctx, func() error {
+		return gomail.Send(c, msg)
+	})
+}
+
+// Send implements gomail.Sender.
+func (c *smtpConn) Send(from string, to []string, msg io.WriterTo) error {
+
+	if err := c.client.Mail(from); err != nil {
+		return err
+	}
+
+	for _, addr := range to {
+		if err := c.client.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := msg.WriteTo(w); err != nil {
+		w.Close()
+		return err
+	}
+
+	return w.Close()
+}
+
+// close quits SMTP session and closes the connection. Errors are ignored as
+// messages have been accepted by server already.
+func (c *smtpConn) close(ctx context.Context) {
+	c.do(ctx, c.client.Quit)
+	c.conn.Close()
+}
+
+// loginAuth implements LOGIN mechanism, which gomail uses if server doesn't
+// support PLAIN (e.g. Office 365).
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+
+	if !server.TLS {
+
+		advertised := false
+		for _, mechanism := range server.Auth {
+			if mechanism == "LOGIN" {
+				advertised = true
+				break
+			}
+		}
+
+		if !advertised {
+			return "", nil, errors.New("mailer: unencrypted connection")
+		}
+	}
+
+	if server.Name != a.host {
+		return "", nil, errors.New("mailer: wrong host name")
+	}
+
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+
+	if !more {
+		return nil, nil
+	}
+
+	switch {
+	case bytes.Equal(fromServer, []byte("Username:")):
+		return []byte(a.username), nil
+	case bytes.Equal(fromServer, []byte("Password:")):
+		return []byte(a.password), nil
+	}
+
+	return nil, fmt.Errorf("mailer: unexpected server challenge: %s", fromServer)
+}
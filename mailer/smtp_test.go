@@ -0,0 +1,306 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"gopkg.in/gomail.v2"
+)
+
+// testSMTPServer is a minimal SMTP server which accepts all messages except
+// for recipients containing "reject".
+type testSMTPServer struct {
+	listener   net.Listener
+	extensions []string
+
+	// Connection is dropped after accepting the number of messages if set
+	messagesPerConn int
+
+	mu       sync.Mutex
+	commands []string
+	messages int
+}
+
+func runTestSMTPServer(t *testing.T, extensions ...string) *testSMTPServer {
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testSMTPServer{
+		listener:   ln,
+		extensions: extensions,
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go s.serve(conn)
+		}
+	}()
+
+	t.Cleanup(func() {
+		ln.Close()
+	})
+
+	return s
+}
+
+func (s *testSMTPServer) serve(conn net.Conn) {
+
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(format string, args ...interface{}) {
+		fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+
+	reply("220 localhost ESMTP")
+
+	accepted := 0
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		s.mu.Lock()
+		s.commands = append(s.commands, cmd)
+		s.mu.Unlock()
+
+		switch cmd {
+		case "EHLO":
+			reply("250-localhost")
+			for _, ext := range s.extensions {
+				reply("250-%s", ext)
+			}
+			reply("250 8BITMIME")
+		case "STARTTLS":
+			reply("454 TLS not available")
+		case "RCPT":
+			if strings.Contains(line, "reject") {
+				reply("550 No such user")
+			} else {
+				reply("250 OK")
+			}
+		case "DATA":
+			reply("354 Go ahead")
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+
+				if l == ".\r\n" {
+					break
+				}
+			}
+
+			s.mu.Lock()
+			s.messages++
+			s.mu.Unlock()
+
+			reply("250 Queued")
+
+			accepted++
+			if accepted == s.messagesPerConn {
+				return
+			}
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func (s *testSMTPServer) received() ([]string, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...), s.messages
+}
+
+func startTestMailer(t *testing.T, scope string, addr string) *Mailer {
+
+	logger = zap.NewNop()
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &Mailer{
+		logger: logger,
+		scope:  scope,
+	}
+
+	m.initDefaultConfigs()
+	viper.Set(m.getConfigPath("host"), host)
+	viper.Set(m.getConfigPath("port"), port)
+	viper.Set(m.getConfigPath("from"), "sender@example.com")
+	viper.Set(m.getConfigPath("retry_interval"), 10*time.Millisecond)
+
+	if err := m.onStart(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return m
+}
+
+func newTestMessage(m *Mailer, to string) *gomail.Message {
+	msg := m.NewMessage()
+	msg.SetHeader("From", m.from)
+	msg.SetHeader("To", to)
+	msg.SetHeader("Subject", "Test")
+	msg.SetBody("text/plain", "Hello")
+	return msg
+}
+
+func TestSend(t *testing.T) {
+
+	srv := runTestSMTPServer(t)
+	m := startTestMailer(t, "mailer_send", srv.listener.Addr().String())
+
+	if err := m.Send(newTestMessage(m, "user@example.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, messages := srv.received(); messages != 1 {
+		t.Errorf("expected 1 message, got %d", messages)
+	}
+}
+
+func TestSendContextClosesConnection(t *testing.T) {
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	closed := make(chan struct{})
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// Never greets, so client hangs until connection is closed
+		io.Copy(io.Discard, conn)
+		close(closed)
+	}()
+
+	m := startTestMailer(t, "mailer_hung", ln.Addr().String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	if err := m.SendContext(ctx, newTestMessage(m, "user@example.com")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Error("connection was not closed after context is done")
+	}
+}
+
+func TestEncryption(t *testing.T) {
+
+	srv := runTestSMTPServer(t, "STARTTLS")
+	addr := srv.listener.Addr().String()
+
+	// Server fails STARTTLS, so the attempt can be told by the error
+	viper.Set("mailer_opportunistic.retries", 0)
+	m := startTestMailer(t, "mailer_opportunistic", addr)
+	if err := m.Send(newTestMessage(m, "user@example.com")); err == nil {
+		t.Error("expected STARTTLS to be attempted if encryption is not specified")
+	}
+
+	viper.Set("mailer_none.encryption", EncryptionNone)
+	m = startTestMailer(t, "mailer_none", addr)
+	if err := m.Send(newTestMessage(m, "user@example.com")); err != nil {
+		t.Errorf("expected no STARTTLS if encryption is none: %v", err)
+	}
+
+	plain := runTestSMTPServer(t)
+
+	viper.Set("mailer_starttls.encryption", EncryptionSTARTTLS)
+	viper.Set("mailer_starttls.retries", 0)
+	m = startTestMailer(t, "mailer_starttls", plain.listener.Addr().String())
+	if err := m.Send(newTestMessage(m, "user@example.com")); err == nil {
+		t.Error("expected failure if server doesn't support STARTTLS")
+	}
+
+	if _, messages := plain.received(); messages != 0 {
+		t.Errorf("expected no message sent without STARTTLS, got %d", messages)
+	}
+}
+
+func TestSendBatch(t *testing.T) {
+
+	srv := runTestSMTPServer(t)
+	srv.messagesPerConn = 2
+
+	m := startTestMailer(t, "mailer_batch", srv.listener.Addr().String())
+
+	msgs := []*gomail.Message{
+		newTestMessage(m, "user1@example.com"),
+		newTestMessage(m, "reject@example.com"),
+		newTestMessage(m, "user2@example.com"),
+		newTestMessage(m, "user3@example.com"),
+		newTestMessage(m, "user4@example.com"),
+	}
+
+	err := m.SendBatch(msgs)
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected BatchError, got %v", err)
+	}
+
+	if _, ok := batchErr.Errors[1]; !ok || len(batchErr.Errors) != 1 {
+		t.Errorf("expected only message #1 to fail: %v", err)
+	}
+
+	commands, messages := srv.received()
+	if messages != 4 {
+		t.Errorf("expected 4 messages, got %d", messages)
+	}
+
+	// Rejected message doesn't close connection, but server drops it after
+	// every 2 messages
+	connections := 0
+	for _, cmd := range commands {
+		if cmd == "EHLO" {
+			connections++
+		}
+	}
+
+	if connections != 2 {
+		t.Errorf("expected 2 connections, got %d", connections)
+	}
+}
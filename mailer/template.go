@@ -0,0 +1,156 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const TemplatePattern = "*.html"
+
+var (
+	htmlBlockPattern   = regexp.MustCompile(`(?is)<(style|script|head)[^>]*>.*?</(style|script|head)>`)
+	htmlNewlinePattern = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|h[1-6]|li|tr|table)>`)
+	htmlTagPattern     = regexp.MustCompile(`<[^>]*>`)
+	blankLinesPattern  = regexp.MustCompile(`\n\s*\n\s*\n+`)
+)
+
+// RegisterTemplates uses templates from fsys (e.g. embed.FS) instead of
+// loading them from template directory.
+func (m *Mailer) RegisterTemplates(fsys fs.FS) error {
+
+	tmpl, err := template.ParseFS(fsys, TemplatePattern)
+	if err != nil {
+		return err
+	}
+
+	m.tmplMu.Lock()
+	defer m.tmplMu.Unlock()
+
+	m.templateFS = fsys
+	m.templates = tmpl
+	m.templatesModTime = time.Time{}
+
+	return nil
+}
+
+// SendTemplate renders named template with data and sends it as HTML body,
+// along with plain text alternative generated from the HTML.
+func (m *Mailer) SendTemplate(to []string, subject string, templateName string, data interface{}) error {
+
+	tmpl, err := m.getTemplates()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, templateName, data); err != nil {
+		return err
+	}
+
+	body := buf.String()
+
+	msg := m.NewMessage()
+	msg.SetHeader("From", m.from)
+	msg.SetHeader("To", to...)
+	msg.SetHeader("Subject", subject)
+	msg.SetBody("text/plain", htmlToText(body))
+	msg.AddAlternative("text/html", body)
+
+	return m.Send(msg)
+}
+
+func (m *Mailer) getTemplates() (*template.Template, error) {
+
+	m.tmplMu.Lock()
+	defer m.tmplMu.Unlock()
+
+	// Templates registered by caller
+	if m.templateFS != nil {
+		return m.templates, nil
+	}
+
+	if len(m.templateDir) == 0 {
+		return nil, fmt.Errorf("mailer: no template was registered and template_dir is not set")
+	}
+
+	if m.templates != nil && !m.templateReload {
+		return m.templates, nil
+	}
+
+	fsys := os.DirFS(m.templateDir)
+
+	modTime, err := latestModTime(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.templates != nil && !modTime.After(m.templatesModTime) {
+		return m.templates, nil
+	}
+
+	tmpl, err := template.ParseFS(fsys, TemplatePattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.templates != nil {
+		m.logger.Info("Reloaded templates", zap.String("dir", m.templateDir))
+	}
+
+	m.templates = tmpl
+	m.templatesModTime = modTime
+
+	return tmpl, nil
+}
+
+func latestModTime(fsys fs.FS) (time.Time, error) {
+
+	var latest time.Time
+
+	matches, err := fs.Glob(fsys, TemplatePattern)
+	if err != nil {
+		return latest, err
+	}
+
+	for _, name := range matches {
+
+		info, err := fs.Stat(fsys, name)
+		if err != nil {
+			return latest, err
+		}
+
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest, nil
+}
+
+// htmlToText converts HTML to readable plain text
+func htmlToText(s string) string {
+
+	s = htmlBlockPattern.ReplaceAllString(s, "")
+	s = htmlNewlinePattern.ReplaceAllString(s, "\n")
+	s = htmlTagPattern.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+
+	s = strings.Join(lines, "\n")
+	s = blankLinesPattern.ReplaceAllString(s, "\n\n")
+
+	return strings.TrimSpace(s)
+}
@@ -3,29 +3,39 @@ package nats_connector
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"github.com/spf13/viper"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
 
-var logger *zap.Logger
-
 const (
 	DefaultHost                = "0.0.0.0:32803"
 	DefaultPingInterval        = 10
 	DefaultMaxPingsOutstanding = 3
 	DefaultMaxReconnects       = -1
 	DefaultAccessKey           = ""
+	DefaultInboxPrefix         = "_INBOX"
+	DefaultReconnectWait       = 2   // seconds
+	DefaultReconnectJitter     = 100 // milliseconds
+	DefaultReconnectJitterTLS  = 1000
 )
 
 type NATSConnector struct {
-	logger *zap.Logger
-	conn   *nats.Conn
-	js     nats.JetStreamContext
-	scope  string
+	logger    *zap.Logger
+	conn      *nats.Conn
+	js        nats.JetStreamContext
+	jetStream jetstream.JetStream
+	scope     string
+
+	mu               sync.RWMutex
+	lameDuckHandlers []func()
+	subscriptions    []*nats.Subscription
 }
 
 type Params struct {
@@ -35,35 +45,72 @@ type Params struct {
 	Logger    *zap.Logger
 }
 
+// Module provides a NATSConnector both as an unnamed instance and as an
+// instance named after scope. Only one Module can be used in an application,
+// since fx rejects a second provider of the unnamed *NATSConnector. Use
+// NamedModule for the other connectors when more than one is needed.
 func Module(scope string) fx.Option {
+	return fx.Module(
+		scope,
+		namedOptions(scope),
+		fx.Provide(
+			fx.Annotate(
+				func(c *NATSConnector) *NATSConnector {
+					return c
+				},
+				fx.ParamTags(NameTag(scope)),
+			),
+		),
+	)
+}
 
-	var c *NATSConnector
-
+// NamedModule provides a NATSConnector only as an instance named after scope,
+// which can be requested with the `name:"<scope>"` tag (see NameTag).
+func NamedModule(scope string) fx.Option {
 	return fx.Module(
 		scope,
-		fx.Provide(func(p Params) *NATSConnector {
+		namedOptions(scope),
+	)
+}
 
-			logger = p.Logger.Named(scope)
+// NameTag returns the fx tag of the connector provided for scope, to be used
+// with fx.ParamTags.
+func NameTag(scope string) string {
+	return fmt.Sprintf("name:%q", scope)
+}
 
-			hs := &NATSConnector{
-				logger: logger,
-				scope:  scope,
-			}
+func namedOptions(scope string) fx.Option {
+	return fx.Options(
+		fx.Provide(
+			fx.Annotate(
+				func(p Params) *NATSConnector {
 
-			hs.initDefaultConfigs()
+					c := &NATSConnector{
+						logger: p.Logger.Named(scope),
+						scope:  scope,
+					}
 
-			return hs
-		}),
-		fx.Populate(&c),
-		fx.Invoke(func(p Params) {
+					c.initDefaultConfigs()
 
-			p.Lifecycle.Append(
-				fx.Hook{
-					OnStart: c.onStart,
-					OnStop:  c.onStop,
+					return c
+				},
+				fx.ResultTags(NameTag(scope)),
+			),
+		),
+		fx.Invoke(
+			fx.Annotate(
+				func(lc fx.Lifecycle, c *NATSConnector) {
+
+					lc.Append(
+						fx.Hook{
+							OnStart: c.onStart,
+							OnStop:  c.onStop,
+						},
+					)
 				},
-			)
-		}),
+				fx.ParamTags(``, NameTag(scope)),
+			),
+		),
 	)
 }
 
@@ -76,6 +123,10 @@ func (c *NATSConnector) initDefaultConfigs() {
 	viper.SetDefault(c.getConfigPath("pingInterval"), DefaultPingInterval)
 	viper.SetDefault(c.getConfigPath("maxPingsOutstanding"), DefaultMaxPingsOutstanding)
 	viper.SetDefault(c.getConfigPath("maxReconnects"), DefaultMaxReconnects)
+	viper.SetDefault(c.getConfigPath("inbox_prefix"), DefaultInboxPrefix)
+	viper.SetDefault(c.getConfigPath("reconnect_wait"), DefaultReconnectWait)
+	viper.SetDefault(c.getConfigPath("reconnect_jitter"), DefaultReconnectJitter)
+	viper.SetDefault(c.getConfigPath("reconnect_jitter_tls"), DefaultReconnectJitterTLS)
 }
 
 func (c *NATSConnector) onStart(ctx context.Context) error {
@@ -85,6 +136,10 @@ func (c *NATSConnector) onStart(ctx context.Context) error {
 	pingInterval := viper.GetInt64(c.getConfigPath("pingInterval"))
 	maxPingsOutstanding := viper.GetInt(c.getConfigPath("maxPingsOutstanding"))
 	maxReconnects := viper.GetInt(c.getConfigPath("maxReconnects"))
+	reconnectWait := viper.GetInt64(c.getConfigPath("reconnect_wait"))
+	reconnectJitter := viper.GetInt64(c.getConfigPath("reconnect_jitter"))
+	reconnectJitterTLS := viper.GetInt64(c.getConfigPath("reconnect_jitter_tls"))
+	inboxPrefix := strings.TrimSuffix(viper.GetString(c.getConfigPath("inbox_prefix")), ".")
 
 	// Authentication and TLS configurations
 	creds := viper.GetString(c.getConfigPath("auth.creds"))
@@ -93,7 +148,7 @@ func (c *NATSConnector) onStart(ctx context.Context) error {
 	tlskey := viper.GetString(c.getConfigPath("tls.key"))
 	tlsca := viper.GetString(c.getConfigPath("tls.ca"))
 
-	logger.Info("Starting NATSConnector",
+	c.logger.Info("Starting NATSConnector",
 		zap.String("host", host),
 	)
 
@@ -102,10 +157,25 @@ func (c *NATSConnector) onStart(ctx context.Context) error {
 		nats.PingInterval(time.Duration(pingInterval) * time.Second),
 		nats.MaxPingsOutstanding(maxPingsOutstanding),
 		nats.MaxReconnects(maxReconnects),
+		nats.ReconnectWait(time.Duration(reconnectWait) * time.Second),
+		nats.ReconnectJitter(
+			time.Duration(reconnectJitter)*time.Millisecond,
+			time.Duration(reconnectJitterTLS)*time.Millisecond,
+		),
+		nats.LameDuckModeHandler(c.lameDuckModeHandler),
 		//		nats.ReconnectHandler(eb.ReconnectHandler),
 		//		nats.DisconnectHandler(eb.handler.Disconnect),
 	}
 
+	if inboxPrefix != DefaultInboxPrefix {
+
+		if err := validateInboxPrefix(inboxPrefix); err != nil {
+			return err
+		}
+
+		opts = append(opts, nats.CustomInboxPrefix(inboxPrefix))
+	}
+
 	if len(creds) > 0 {
 		opts = append(opts, nats.UserCredentials(creds))
 	} else if len(nkey) > 0 {
@@ -135,12 +205,54 @@ func (c *NATSConnector) onStart(ctx context.Context) error {
 		return err
 	}
 
+	c.jetStream, err = jetstream.New(nc)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *NATSConnector) lameDuckModeHandler(nc *nats.Conn) {
+
+	c.logger.Warn("Server entered lame duck mode",
+		zap.String("server", nc.ConnectedUrlRedacted()),
+	)
+
+	c.mu.RLock()
+	handlers := make([]func(), len(c.lameDuckHandlers))
+	copy(handlers, c.lameDuckHandlers)
+	c.mu.RUnlock()
+
+	for _, fn := range handlers {
+		fn()
+	}
+}
+
+func validateInboxPrefix(prefix string) error {
+
+	if len(prefix) == 0 {
+		return fmt.Errorf("nats_connector: inbox prefix cannot be empty")
+	}
+
+	for _, token := range strings.Split(prefix, ".") {
+
+		if len(token) == 0 {
+			return fmt.Errorf("nats_connector: invalid inbox prefix \"%s\": empty token", prefix)
+		}
+
+		if token == "*" || token == ">" || strings.ContainsAny(token, "*> \t\r\n") {
+			return fmt.Errorf("nats_connector: invalid inbox prefix \"%s\": illegal character in token \"%s\"", prefix, token)
+		}
+	}
+
 	return nil
 }
 
 func (c *NATSConnector) onStop(ctx context.Context) error {
+	c.drainSubscriptions(ctx)
 	c.conn.Close()
-	logger.Info("Stopped NATSConnector")
+	c.logger.Info("Stopped NATSConnector")
 	return nil
 }
 
@@ -151,3 +263,15 @@ func (c *NATSConnector) GetConnection() *nats.Conn {
 func (c *NATSConnector) GetJetStreamContext() nats.JetStreamContext {
 	return c.js
 }
+
+func (c *NATSConnector) GetJetStream() jetstream.JetStream {
+	return c.jetStream
+}
+
+// OnLameDuck registers a callback which is invoked when the connected server
+// enters lame duck mode, before it closes the connection.
+func (c *NATSConnector) OnLameDuck(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lameDuckHandlers = append(c.lameDuckHandlers, fn)
+}
@@ -0,0 +1,81 @@
+package nats_connector
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+func startTestConnector(t *testing.T, scope string, url string) *NATSConnector {
+
+	c := &NATSConnector{
+		logger: zap.NewNop(),
+		scope:  scope,
+	}
+
+	c.initDefaultConfigs()
+	viper.Set(c.getConfigPath("host"), url)
+
+	if err := c.onStart(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		c.onStop(context.Background())
+	})
+
+	return c
+}
+
+func TestCustomInboxPrefix(t *testing.T) {
+
+	srv := runTestServer(t)
+
+	viper.Set("nats_inbox.inbox_prefix", "_CUSTOM.app.")
+	c := startTestConnector(t, "nats_inbox", srv.ClientURL())
+
+	replies := make(chan string, 1)
+
+	_, err := c.GetConnection().Subscribe("svc.echo", func(msg *nats.Msg) {
+		replies <- msg.Reply
+		msg.Respond(msg.Data)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := c.GetConnection().Request("svc.echo", []byte("hello"), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(resp.Data) != "hello" {
+		t.Errorf("unexpected reply %q", resp.Data)
+	}
+
+	if reply := <-replies; !strings.HasPrefix(reply, "_CUSTOM.app.") {
+		t.Errorf("reply subject %q does not use custom inbox prefix", reply)
+	}
+}
+
+func TestValidateInboxPrefix(t *testing.T) {
+
+	valid := []string{"_INBOX", "_CUSTOM.app", "a.b.c"}
+	for _, prefix := range valid {
+		if err := validateInboxPrefix(prefix); err != nil {
+			t.Errorf("expected %q to be valid: %v", prefix, err)
+		}
+	}
+
+	invalid := []string{"", "a..b", "a.*", "a.>", "a b", "a.b*"}
+	for _, prefix := range invalid {
+		if err := validateInboxPrefix(prefix); err == nil {
+			t.Errorf("expected %q to be invalid", prefix)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+//go:build !windows
+
+package nats_connector
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+func TestOnLameDuck(t *testing.T) {
+
+	// Lame duck mode is triggered by SIGUSR2, so the server has to handle
+	// signals
+	srv := runTestServerWithOptions(t, func(opts *server.Options) {
+		opts.NoSigs = false
+	})
+
+	c := startTestConnector(t, "nats_ldm", srv.ClientURL())
+
+	called := make(chan struct{}, 2)
+	for i := 0; i < 2; i++ {
+		c.OnLameDuck(func() {
+			called <- struct{}{}
+		})
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-called:
+		case <-time.After(5 * time.Second):
+			t.Fatal("lame duck callback was not invoked")
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package nats_connector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxtest"
+	"go.uber.org/zap"
+)
+
+func TestNamedModules(t *testing.T) {
+
+	srvA := runTestServer(t)
+	srvB := runTestServer(t)
+
+	viper.Set("nats_a.host", srvA.ClientURL())
+	viper.Set("nats_b.host", srvB.ClientURL())
+
+	var def, a, b *NATSConnector
+
+	app := fxtest.New(t,
+		fx.Provide(zap.NewNop),
+		Module("nats_a"),
+		NamedModule("nats_b"),
+		fx.Invoke(
+			fx.Annotate(
+				func(d *NATSConnector, ca *NATSConnector, cb *NATSConnector) {
+					def, a, b = d, ca, cb
+				},
+				fx.ParamTags(``, NameTag("nats_a"), NameTag("nats_b")),
+			),
+		),
+	)
+
+	app.RequireStart()
+	defer app.RequireStop()
+
+	if def != a {
+		t.Error("expected unnamed connector to be the one of Module")
+	}
+
+	if a == b {
+		t.Fatal("expected separate connectors")
+	}
+
+	if url := a.GetConnection().ConnectedUrl(); url != srvA.ClientURL() {
+		t.Errorf("connector a is connected to %s", url)
+	}
+
+	if url := b.GetConnection().ConnectedUrl(); url != srvB.ClientURL() {
+		t.Errorf("connector b is connected to %s", url)
+	}
+}
+
+func TestModuleCannotBeUsedTwice(t *testing.T) {
+
+	app := fx.New(
+		fx.NopLogger,
+		fx.Provide(zap.NewNop),
+		Module("nats_dup_a"),
+		Module("nats_dup_b"),
+		fx.Invoke(func(c *NATSConnector) {}),
+	)
+
+	err := app.Err()
+	if err == nil {
+		t.Fatal("expected duplicate unnamed connector to be rejected")
+	}
+
+	for _, s := range []string{"*nats_connector.NATSConnector", "already provided"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("expected error to mention %q: %v", s, err)
+		}
+	}
+}
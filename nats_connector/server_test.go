@@ -0,0 +1,46 @@
+package nats_connector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+// runTestServer starts an embedded NATS server with JetStream enabled, which
+// is shut down when the test finishes.
+func runTestServer(t *testing.T) *server.Server {
+	return runTestServerWithOptions(t, func(opts *server.Options) {})
+}
+
+func runTestServerWithOptions(t *testing.T, configure func(opts *server.Options)) *server.Server {
+
+	opts := &server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+
+		LameDuckGracePeriod: 100 * time.Millisecond,
+		LameDuckDuration:    time.Second,
+	}
+
+	configure(opts)
+
+	srv, err := server.NewServer(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.Start()
+
+	if !srv.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server is not ready")
+	}
+
+	t.Cleanup(srv.Shutdown)
+
+	return srv
+}
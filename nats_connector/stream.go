@@ -0,0 +1,153 @@
+package nats_connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+// Stream configuration fields which cannot be changed once a stream exists.
+// Zero values of retention and storage are meaningful (limits, file), so
+// those are always compared.
+var immutableStreamFields = []string{
+	"Retention",
+	"Storage",
+}
+
+var immutableOptionalStreamFields = []string{
+	"MaxConsumers",
+	"Mirror",
+}
+
+type streamConfigChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+type IncompatibleStreamConfigError struct {
+	Stream string
+	Fields []string
+}
+
+func (e *IncompatibleStreamConfigError) Error() string {
+	return fmt.Sprintf("nats_connector: stream \"%s\" cannot be updated, incompatible fields: %s",
+		e.Stream,
+		strings.Join(e.Fields, ", "),
+	)
+}
+
+// EnsureStream creates the stream if it does not exist yet, or updates it
+// when the existing configuration differs from cfg. Fields left as zero
+// values in cfg are considered unspecified, so they are neither compared nor
+// changed on update, except for the ones which cannot be changed after
+// creation. As a result, EnsureStream cannot reset a field to its zero value.
+func (c *NATSConnector) EnsureStream(ctx context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error) {
+
+	s, err := c.jetStream.Stream(ctx, cfg.Name)
+	if err != nil {
+		if !errors.Is(err, jetstream.ErrStreamNotFound) {
+			return nil, err
+		}
+
+		c.logger.Info("Creating stream",
+			zap.String("stream", cfg.Name),
+		)
+
+		return c.jetStream.CreateStream(ctx, cfg)
+	}
+
+	current := s.CachedInfo().Config
+
+	incompatible := append(
+		diffStreamConfig(current, cfg, immutableStreamFields, true),
+		diffStreamConfig(current, cfg, immutableOptionalStreamFields, false)...,
+	)
+	if len(incompatible) > 0 {
+		return nil, &IncompatibleStreamConfigError{
+			Stream: cfg.Name,
+			Fields: incompatible,
+		}
+	}
+
+	changed := diffStreamConfig(current, cfg, nil, false)
+	if len(changed) == 0 {
+		return s, nil
+	}
+
+	c.logger.Info("Updating stream",
+		zap.String("stream", cfg.Name),
+		zap.Any("diff", streamConfigDiff(current, cfg, changed)),
+	)
+
+	return c.jetStream.UpdateStream(ctx, mergeStreamConfig(current, cfg))
+}
+
+// mergeStreamConfig overlays fields of desired configuration which are not
+// zero values onto current configuration.
+func mergeStreamConfig(current jetstream.StreamConfig, desired jetstream.StreamConfig) jetstream.StreamConfig {
+
+	merged := current
+
+	mv := reflect.ValueOf(&merged).Elem()
+	dv := reflect.ValueOf(desired)
+
+	for i := 0; i < dv.NumField(); i++ {
+		if f := dv.Field(i); !f.IsZero() {
+			mv.Field(i).Set(f)
+		}
+	}
+
+	return merged
+}
+
+// diffStreamConfig returns names of fields which differ between current and
+// desired configuration. If fields is empty, all fields are compared.
+func diffStreamConfig(current jetstream.StreamConfig, desired jetstream.StreamConfig, fields []string, includeZero bool) []string {
+
+	cv := reflect.ValueOf(current)
+	dv := reflect.ValueOf(desired)
+
+	if len(fields) == 0 {
+		t := dv.Type()
+		for i := 0; i < t.NumField(); i++ {
+			fields = append(fields, t.Field(i).Name)
+		}
+	}
+
+	changed := make([]string, 0)
+	for _, name := range fields {
+
+		d := dv.FieldByName(name)
+		if !includeZero && d.IsZero() {
+			continue
+		}
+
+		if !reflect.DeepEqual(cv.FieldByName(name).Interface(), d.Interface()) {
+			changed = append(changed, name)
+		}
+	}
+
+	return changed
+}
+
+// streamConfigDiff returns old and new values of the given fields.
+func streamConfigDiff(current jetstream.StreamConfig, desired jetstream.StreamConfig, fields []string) map[string]streamConfigChange {
+
+	cv := reflect.ValueOf(current)
+	dv := reflect.ValueOf(desired)
+
+	diff := make(map[string]streamConfigChange, len(fields))
+	for _, name := range fields {
+		diff[name] = streamConfigChange{
+			Old: cv.FieldByName(name).Interface(),
+			New: dv.FieldByName(name).Interface(),
+		}
+	}
+
+	return diff
+}
@@ -0,0 +1,143 @@
+package nats_connector
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestEnsureStream(t *testing.T) {
+
+	srv := runTestServer(t)
+	c := startTestConnector(t, "nats_stream", srv.ClientURL())
+
+	core, logs := observer.New(zap.InfoLevel)
+	c.logger = zap.New(core)
+
+	ctx := context.Background()
+
+	cfg := jetstream.StreamConfig{
+		Name:     "ORDERS",
+		Subjects: []string{"orders.>"},
+		MaxMsgs:  100,
+	}
+
+	// Create
+	s, err := c.EnsureStream(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.CachedInfo().Config.MaxMsgs != 100 {
+		t.Errorf("unexpected max msgs %d", s.CachedInfo().Config.MaxMsgs)
+	}
+
+	if logs.FilterMessage("Creating stream").Len() != 1 {
+		t.Error("expected stream to be created")
+	}
+
+	// Reuse
+	if _, err := c.EnsureStream(ctx, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if logs.FilterMessage("Updating stream").Len() != 0 {
+		t.Error("expected unchanged stream not to be updated")
+	}
+
+	// Update
+	cfg.MaxMsgs = 200
+	s, err = c.EnsureStream(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.CachedInfo().Config.MaxMsgs != 200 {
+		t.Errorf("unexpected max msgs %d", s.CachedInfo().Config.MaxMsgs)
+	}
+
+	updates := logs.FilterMessage("Updating stream").All()
+	if len(updates) != 1 {
+		t.Fatalf("expected one update log, got %d", len(updates))
+	}
+
+	diff, ok := updates[0].ContextMap()["diff"].(map[string]streamConfigChange)
+	if !ok {
+		t.Fatalf("unexpected diff %#v", updates[0].ContextMap()["diff"])
+	}
+
+	if len(diff) != 1 || diff["MaxMsgs"].Old != int64(100) || diff["MaxMsgs"].New != int64(200) {
+		t.Errorf("unexpected diff %#v", diff)
+	}
+
+	// Incompatible
+	cfg.Storage = jetstream.MemoryStorage
+	_, err = c.EnsureStream(ctx, cfg)
+
+	var incompatible *IncompatibleStreamConfigError
+	if !errors.As(err, &incompatible) {
+		t.Fatalf("expected IncompatibleStreamConfigError, got %v", err)
+	}
+
+	if len(incompatible.Fields) != 1 || incompatible.Fields[0] != "Storage" {
+		t.Errorf("unexpected incompatible fields %v", incompatible.Fields)
+	}
+
+	info, err := s.Info(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Config.Storage != jetstream.FileStorage {
+		t.Error("expected incompatible config not to be applied")
+	}
+}
+
+func TestEnsureStreamKeepsUnspecifiedFields(t *testing.T) {
+
+	srv := runTestServer(t)
+	c := startTestConnector(t, "nats_stream_merge", srv.ClientURL())
+
+	ctx := context.Background()
+
+	_, err := c.EnsureStream(ctx, jetstream.StreamConfig{
+		Name:     "EVENTS",
+		Subjects: []string{"events.>"},
+		MaxAge:   time.Hour,
+		MaxMsgs:  100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Neither subjects nor max age is specified
+	s, err := c.EnsureStream(ctx, jetstream.StreamConfig{
+		Name:    "EVENTS",
+		MaxMsgs: 200,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := s.Info(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Config.MaxMsgs != 200 {
+		t.Errorf("expected max msgs to be updated, got %d", info.Config.MaxMsgs)
+	}
+
+	if info.Config.MaxAge != time.Hour {
+		t.Errorf("expected max age to be kept, got %s", info.Config.MaxAge)
+	}
+
+	if len(info.Config.Subjects) != 1 || info.Config.Subjects[0] != "events.>" {
+		t.Errorf("expected subjects to be kept, got %v", info.Config.Subjects)
+	}
+}
@@ -0,0 +1,113 @@
+package nats_connector
+
+import (
+	"context"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// SubscribeManaged subscribes to subject through JetStream and keeps track of
+// the subscription, so it will be drained automatically when the connector
+// stops. Subject has to be bound to a stream.
+func (c *NATSConnector) SubscribeManaged(subject string, cb nats.MsgHandler, opts ...nats.SubOpt) (*nats.Subscription, error) {
+
+	sub, err := c.subscribe(subject, "", cb, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	c.trackSubscription(sub)
+
+	return sub, nil
+}
+
+// QueueSubscribeManaged is the queue group variant of SubscribeManaged.
+func (c *NATSConnector) QueueSubscribeManaged(subject string, queue string, cb nats.MsgHandler, opts ...nats.SubOpt) (*nats.Subscription, error) {
+
+	sub, err := c.subscribe(subject, queue, cb, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	c.trackSubscription(sub)
+
+	return sub, nil
+}
+
+// ActiveSubscriptions returns the number of managed subscriptions which are
+// still valid.
+func (c *NATSConnector) ActiveSubscriptions() int {
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	count := 0
+	for _, sub := range c.subscriptions {
+		if sub.IsValid() {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (c *NATSConnector) subscribe(subject string, queue string, cb nats.MsgHandler, opts ...nats.SubOpt) (*nats.Subscription, error) {
+
+	if len(queue) > 0 {
+		return c.js.QueueSubscribe(subject, queue, cb, opts...)
+	}
+
+	return c.js.Subscribe(subject, cb, opts...)
+}
+
+// trackSubscription adds sub to managed subscriptions, dropping the ones
+// which were unsubscribed by caller in the meantime.
+func (c *NATSConnector) trackSubscription(sub *nats.Subscription) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	subs := c.subscriptions[:0]
+	for _, s := range c.subscriptions {
+		if s.IsValid() {
+			subs = append(subs, s)
+		}
+	}
+
+	c.subscriptions = append(subs, sub)
+}
+
+func (c *NATSConnector) drainSubscriptions(ctx context.Context) {
+
+	c.mu.Lock()
+	subs := c.subscriptions
+	c.subscriptions = nil
+	c.mu.Unlock()
+
+	for _, sub := range subs {
+
+		if !sub.IsValid() {
+			continue
+		}
+
+		if err := sub.Drain(); err != nil {
+			c.logger.Warn("Failed to drain subscription",
+				zap.String("subject", sub.Subject),
+				zap.Error(err),
+			)
+		}
+	}
+
+	// Wait for pending messages to be processed
+	for _, sub := range subs {
+		for sub.IsValid() {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package nats_connector
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxtest"
+	"go.uber.org/zap"
+)
+
+func TestDrainSubscriptionsOnStop(t *testing.T) {
+
+	srv := runTestServer(t)
+	viper.Set("nats_drain.host", srv.ClientURL())
+
+	var c *NATSConnector
+
+	app := fxtest.New(t,
+		fx.Provide(zap.NewNop),
+		Module("nats_drain"),
+		fx.Populate(&c),
+	)
+
+	app.RequireStart()
+
+	ensureTestStream(t, c, "JOBS", "jobs.>")
+
+	baseline := srv.NumSubscriptions()
+
+	received := make(chan struct{})
+	var processed atomic.Bool
+
+	sub, err := c.SubscribeManaged("jobs.new", func(msg *nats.Msg) {
+		close(received)
+		time.Sleep(100 * time.Millisecond)
+		processed.Store(true)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.GetJetStream().Publish(context.Background(), "jobs.new", []byte("job")); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.ActiveSubscriptions() != 1 {
+		t.Fatalf("expected 1 active subscription, got %d", c.ActiveSubscriptions())
+	}
+
+	<-received
+
+	app.RequireStop()
+
+	if !processed.Load() {
+		t.Error("expected pending message to be processed before stop")
+	}
+
+	if sub.IsValid() {
+		t.Error("expected subscription to be removed")
+	}
+
+	if c.ActiveSubscriptions() != 0 {
+		t.Errorf("expected no active subscription, got %d", c.ActiveSubscriptions())
+	}
+
+	if n := srv.NumSubscriptions(); n >= baseline+1 {
+		t.Errorf("expected subscription to be removed from server, got %d subscriptions", n)
+	}
+}
+
+func TestTrackSubscriptionPrunesUnsubscribed(t *testing.T) {
+
+	srv := runTestServer(t)
+	c := startTestConnector(t, "nats_prune", srv.ClientURL())
+
+	ensureTestStream(t, c, "EVENTS", "events.>")
+
+	for i := 0; i < 10; i++ {
+
+		sub, err := c.SubscribeManaged("events.new", func(msg *nats.Msg) {})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := sub.Unsubscribe(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := c.SubscribeManaged("events.new", func(msg *nats.Msg) {}); err != nil {
+		t.Fatal(err)
+	}
+
+	c.mu.RLock()
+	tracked := len(c.subscriptions)
+	c.mu.RUnlock()
+
+	if tracked != 1 {
+		t.Errorf("expected 1 tracked subscription, got %d", tracked)
+	}
+}
+
+func ensureTestStream(t *testing.T, c *NATSConnector, name string, subject string) {
+
+	_, err := c.EnsureStream(context.Background(), jetstream.StreamConfig{
+		Name:     name,
+		Subjects: []string{subject},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSubscribeManagedUsesJetStream(t *testing.T) {
+
+	srv := runTestServer(t)
+	c := startTestConnector(t, "nats_js_sub", srv.ClientURL())
+
+	// Subject which is not bound to any stream
+	if _, err := c.SubscribeManaged("unbound", func(msg *nats.Msg) {}); err == nil {
+		t.Error("expected subscription without stream to fail")
+	}
+
+	ensureTestStream(t, c, "ORDERS", "orders.>")
+
+	received := make(chan *nats.Msg, 1)
+	if _, err := c.QueueSubscribeManaged("orders.>", "workers", func(msg *nats.Msg) {
+		received <- msg
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.GetJetStream().Publish(context.Background(), "orders.new", []byte("order")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-received:
+		if _, err := msg.Metadata(); err != nil {
+			t.Errorf("expected JetStream message: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not received")
+	}
+}
@@ -3,6 +3,9 @@ package postgres_connector
 import (
 	"context"
 	"fmt"
+	"io"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 	"github.com/weedbox/common-modules/database"
@@ -11,6 +14,7 @@ import (
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	gorm_logger "gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
 )
 
 const (
@@ -19,15 +23,29 @@ const (
 	DefaultDbName   = "default"
 	DefaultUser     = "postgres"
 	DefaultPassword = ""
-	DefaultSSLMode  = false
+	DefaultSSLMode  = "disable"
 	DefaultLogLevel = gorm_logger.Error
+
+	DefaultMaxOpenConns    = 0 // unlimited
+	DefaultMaxIdleConns    = 2
+	DefaultConnMaxLifetime = time.Duration(0)
+	DefaultConnMaxIdleTime = time.Duration(0)
+
+	// With defaults, retries wait 7s in total, which fits in the default
+	// start timeout of fx (15s). Raise it with fx.StartTimeout when retrying
+	// for longer, otherwise startup is aborted before retries run out.
+	DefaultConnectRetries       = 3
+	DefaultConnectRetryInterval = time.Second
+	MaxConnectRetryInterval     = 30 * time.Second
 )
 
 type PostgresConnector struct {
-	params Params
-	logger *zap.Logger
-	db     *gorm.DB
-	scope  string
+	params   Params
+	logger   *zap.Logger
+	db       *gorm.DB
+	resolver *dbresolver.DBResolver
+	scope    string
+	models   []interface{}
 }
 
 type Params struct {
@@ -56,6 +74,14 @@ func Module(scope string) fx.Option {
 			return c
 		}),
 		fx.Populate(&dc),
+		fx.Invoke(
+			fx.Annotate(
+				func(models []interface{}) {
+					dc.(*PostgresConnector).models = models
+				},
+				fx.ParamTags(fmt.Sprintf(`group:"%s"`, modelsGroup(scope))),
+			),
+		),
 		fx.Invoke(func(p Params) {
 
 			c := dc.(*PostgresConnector)
@@ -83,23 +109,23 @@ func (c *PostgresConnector) initDefaultConfigs() {
 	viper.SetDefault(c.getConfigPath("password"), DefaultPassword)
 	viper.SetDefault(c.getConfigPath("sslmode"), DefaultSSLMode)
 	viper.SetDefault(c.getConfigPath("loglevel"), DefaultLogLevel)
+	viper.SetDefault(c.getConfigPath("max_open_conns"), DefaultMaxOpenConns)
+	viper.SetDefault(c.getConfigPath("max_idle_conns"), DefaultMaxIdleConns)
+	viper.SetDefault(c.getConfigPath("conn_max_lifetime"), DefaultConnMaxLifetime)
+	viper.SetDefault(c.getConfigPath("conn_max_idle_time"), DefaultConnMaxIdleTime)
+	viper.SetDefault(c.getConfigPath("connect_retries"), DefaultConnectRetries)
+	viper.SetDefault(c.getConfigPath("connect_retry_interval"), DefaultConnectRetryInterval)
 }
 
 func (c *PostgresConnector) onStart(ctx context.Context) error {
 
-	sslmode := "disable"
-	if viper.GetBool(c.getConfigPath("sslmode")) {
-		sslmode = "enable"
-	}
-
-	dsn := fmt.Sprintf("user=%s password=%s dbname=%s host=%s port=%d sslmode=%s",
-		viper.GetString(c.getConfigPath("user")),
-		viper.GetString(c.getConfigPath("password")),
-		viper.GetString(c.getConfigPath("dbname")),
+	dsn, err := c.buildDSN(
 		viper.GetString(c.getConfigPath("host")),
 		viper.GetInt(c.getConfigPath("port")),
-		sslmode,
 	)
+	if err != nil {
+		return err
+	}
 
 	c.logger.Info("Starting PostgresConnector",
 		zap.String("host", viper.GetString(c.getConfigPath("host"))),
@@ -111,15 +137,223 @@ func (c *PostgresConnector) onStart(ctx context.Context) error {
 	opts := &gorm.Config{
 		Logger:         gorm_logger.Default.LogMode(gorm_logger.LogLevel(viper.GetInt(c.getConfigPath("loglevel")))),
 		TranslateError: true,
+
+		// Ping of gorm has no context, open pings with context instead
+		DisableAutomaticPing: true,
 	}
 
-	db, err := gorm.Open(postgres.Open(dsn), opts)
+	db, err := c.connect(ctx, dsn, opts)
 	if err != nil {
 		return err
 	}
 
 	c.db = db
 
+	err = c.setupReplicas()
+	if err != nil {
+		return err
+	}
+
+	err = c.setupConnectionPool()
+	if err != nil {
+		return err
+	}
+
+	return c.Migrate(c.models...)
+}
+
+// connect opens database and retries with exponential backoff if database is
+// not reachable yet.
+func (c *PostgresConnector) connect(ctx context.Context, dsn string, opts *gorm.Config) (*gorm.DB, error) {
+
+	retries := viper.GetInt(c.getConfigPath("connect_retries"))
+	interval := viper.GetDuration(c.getConfigPath("connect_retry_interval"))
+
+	var lastErr error
+
+	for attempt := 0; ; attempt++ {
+
+		db, err := c.open(ctx, dsn, opts)
+		if err == nil {
+			return db, nil
+		}
+
+		// Error of attempt aborted by ctx doesn't tell why connection failed
+		if ctx.Err() == nil || lastErr == nil {
+			lastErr = err
+		}
+
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("postgres_connector: failed to connect to database: %w (last error: %v)", ctx.Err(), lastErr)
+		}
+
+		if attempt >= retries {
+			return nil, err
+		}
+
+		c.logger.Warn("Failed to connect to database, retrying",
+			zap.Int("attempt", attempt+1),
+			zap.Int("retries", retries),
+			zap.Duration("interval", interval),
+			zap.Error(err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("postgres_connector: failed to connect to database: %w (last error: %v)", ctx.Err(), lastErr)
+		case <-time.After(interval):
+		}
+
+		interval *= 2
+		if interval > MaxConnectRetryInterval {
+			interval = MaxConnectRetryInterval
+		}
+	}
+}
+
+func (c *PostgresConnector) open(ctx context.Context, dsn string, opts *gorm.Config) (*gorm.DB, error) {
+
+	db, err := gorm.Open(postgres.Open(dsn), opts)
+	if err != nil {
+
+		// Connection pool might have been created already
+		if db != nil {
+			if sqlDB, e := db.DB(); e == nil {
+				sqlDB.Close()
+			}
+		}
+
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sqlDB.PingContext(ctx); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+var sslModes = map[string]struct{}{
+	"disable":     {},
+	"allow":       {},
+	"prefer":      {},
+	"require":     {},
+	"verify-ca":   {},
+	"verify-full": {},
+}
+
+func (c *PostgresConnector) getSSLMode() (string, error) {
+
+	sslmode := viper.GetString(c.getConfigPath("sslmode"))
+
+	// Compatible with boolean setting of previous versions
+	switch sslmode {
+	case "", "false":
+		return "disable", nil
+	case "true":
+		return "require", nil
+	}
+
+	if _, ok := sslModes[sslmode]; !ok {
+		return "", fmt.Errorf("postgres_connector: invalid sslmode \"%s\"", sslmode)
+	}
+
+	return sslmode, nil
+}
+
+func (c *PostgresConnector) buildDSN(host string, port int) (string, error) {
+
+	sslmode, err := c.getSSLMode()
+	if err != nil {
+		return "", err
+	}
+
+	dsn := fmt.Sprintf("user=%s password=%s dbname=%s host=%s port=%d sslmode=%s",
+		viper.GetString(c.getConfigPath("user")),
+		viper.GetString(c.getConfigPath("password")),
+		viper.GetString(c.getConfigPath("dbname")),
+		host,
+		port,
+		sslmode,
+	)
+
+	// Certificates
+	for _, key := range []string{"sslrootcert", "sslcert", "sslkey"} {
+		if v := viper.GetString(c.getConfigPath(key)); len(v) > 0 {
+			dsn += fmt.Sprintf(" %s=%s", key, quoteDSNValue(v))
+		}
+	}
+
+	// Runtime parameters
+	searchPath := viper.GetString(c.getConfigPath("search_path"))
+	if len(searchPath) > 0 {
+		dsn += fmt.Sprintf(" search_path=%s", quoteDSNValue(searchPath))
+	}
+
+	statementTimeout := viper.GetString(c.getConfigPath("statement_timeout"))
+	if len(statementTimeout) > 0 {
+
+		timeout, err := time.ParseDuration(statementTimeout)
+		if err != nil {
+			return "", fmt.Errorf("postgres_connector: invalid statement_timeout \"%s\": %w", statementTimeout, err)
+		}
+
+		// Postgres takes milliseconds, and 0 disables the timeout
+		if timeout > 0 && timeout < time.Millisecond {
+			return "", fmt.Errorf("postgres_connector: statement_timeout \"%s\" is shorter than 1ms", statementTimeout)
+		}
+
+		dsn += fmt.Sprintf(" statement_timeout=%d", timeout.Milliseconds())
+	}
+
+	return dsn, nil
+}
+
+// quoteDSNValue quotes value of key/value DSN, so it can contain spaces.
+func quoteDSNValue(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
+}
+
+func (c *PostgresConnector) setupConnectionPool() error {
+
+	sqlDB, err := c.db.DB()
+	if err != nil {
+		return err
+	}
+
+	maxOpenConns := viper.GetInt(c.getConfigPath("max_open_conns"))
+	maxIdleConns := viper.GetInt(c.getConfigPath("max_idle_conns"))
+	connMaxLifetime := viper.GetDuration(c.getConfigPath("conn_max_lifetime"))
+	connMaxIdleTime := viper.GetDuration(c.getConfigPath("conn_max_idle_time"))
+
+	sqlDB.SetMaxOpenConns(maxOpenConns)
+	sqlDB.SetMaxIdleConns(maxIdleConns)
+	sqlDB.SetConnMaxLifetime(connMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(connMaxIdleTime)
+
+	if c.resolver != nil {
+		c.resolver.
+			SetMaxOpenConns(maxOpenConns).
+			SetMaxIdleConns(maxIdleConns).
+			SetConnMaxLifetime(connMaxLifetime).
+			SetConnMaxIdleTime(connMaxIdleTime)
+	}
+
+	c.logger.Info("Configured connection pool",
+		zap.Int("max_open_conns", maxOpenConns),
+		zap.Int("max_idle_conns", maxIdleConns),
+		zap.Duration("conn_max_lifetime", connMaxLifetime),
+		zap.Duration("conn_max_idle_time", connMaxIdleTime),
+	)
+
 	return nil
 }
 
@@ -132,9 +366,43 @@ func (c *PostgresConnector) onStop(ctx context.Context) error {
 		return err
 	}
 
+	if c.resolver != nil {
+
+		// Close replica pools, primary database is closed below
+		err := c.resolver.Call(func(pool gorm.ConnPool) error {
+
+			if pool == gorm.ConnPool(db) {
+				return nil
+			}
+
+			if closer, ok := pool.(io.Closer); ok {
+				return closer.Close()
+			}
+
+			return nil
+		})
+		if err != nil {
+			c.logger.Error("Failed to close replica connections", zap.Error(err))
+		}
+	}
+
 	return db.Close()
 }
 
 func (c *PostgresConnector) GetDB() *gorm.DB {
 	return c.db
 }
+
+func (c *PostgresConnector) Ping(ctx context.Context) error {
+
+	if c.db == nil {
+		return fmt.Errorf("postgres_connector: database is not connected")
+	}
+
+	db, err := c.db.DB()
+	if err != nil {
+		return err
+	}
+
+	return db.PingContext(ctx)
+}
@@ -0,0 +1,55 @@
+package postgres_connector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestBuildDSNQuotesValues(t *testing.T) {
+
+	c := &PostgresConnector{scope: "postgres_dsn"}
+	c.initDefaultConfigs()
+
+	viper.Set(c.getConfigPath("sslmode"), "verify-full")
+	viper.Set(c.getConfigPath("sslrootcert"), `/etc/my certs/it's\ca.pem`)
+	viper.Set(c.getConfigPath("search_path"), "app, public")
+
+	dsn, err := c.buildDSN("localhost", 5432)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		`sslrootcert='/etc/my certs/it\'s\\ca.pem'`,
+		`search_path='app, public'`,
+	} {
+		if !strings.Contains(dsn, expected) {
+			t.Errorf("expected DSN to contain %s: %s", expected, dsn)
+		}
+	}
+}
+
+func TestBuildDSNStatementTimeout(t *testing.T) {
+
+	c := &PostgresConnector{scope: "postgres_timeout"}
+	c.initDefaultConfigs()
+
+	viper.Set(c.getConfigPath("statement_timeout"), "1500ms")
+
+	dsn, err := c.buildDSN("localhost", 5432)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(dsn, "statement_timeout=1500") {
+		t.Errorf("unexpected DSN: %s", dsn)
+	}
+
+	viper.Set(c.getConfigPath("statement_timeout"), "500us")
+
+	if _, err := c.buildDSN("localhost", 5432); err == nil {
+		t.Error("expected sub-millisecond statement_timeout to be rejected")
+	}
+}
@@ -0,0 +1,62 @@
+package postgres_connector
+
+import (
+	"fmt"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// RegisterModels registers models to be migrated automatically when the
+// connector of scope starts.
+func RegisterModels(scope string, models ...interface{}) fx.Option {
+	return fx.Provide(
+		fx.Annotate(
+			func() []interface{} {
+				return models
+			},
+			fx.ResultTags(fmt.Sprintf(`group:"%s,flatten"`, modelsGroup(scope))),
+		),
+	)
+}
+
+func modelsGroup(scope string) string {
+	return fmt.Sprintf("postgres_models_%s", scope)
+}
+
+// Migrate runs auto migration for models within a transaction.
+func (c *PostgresConnector) Migrate(models ...interface{}) error {
+
+	if len(models) == 0 {
+		return nil
+	}
+
+	created := 0
+
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+
+		for _, model := range models {
+
+			if !tx.Migrator().HasTable(model) {
+				created++
+			}
+
+			if err := tx.AutoMigrate(model); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	c.logger.Info("Migrated database",
+		zap.Int("tables", len(models)),
+		zap.Int("created", created),
+	)
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package postgres_connector
+
+import (
+	"testing"
+
+	"github.com/weedbox/common-modules/database"
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxtest"
+	"go.uber.org/zap"
+)
+
+type testUser struct {
+	ID uint
+}
+
+type testOrder struct {
+	ID uint
+}
+
+func TestRegisterModelsByScope(t *testing.T) {
+
+	connectors := make(map[string]*PostgresConnector)
+
+	for _, scope := range []string{"postgres_users", "postgres_orders"} {
+
+		var dc database.DatabaseConnector
+
+		app := fxtest.New(t,
+			fx.Provide(zap.NewNop),
+			Module(scope),
+			RegisterModels("postgres_users", &testUser{}),
+			RegisterModels("postgres_orders", &testOrder{}, &testOrder{}),
+			fx.Populate(&dc),
+		)
+
+		if err := app.Err(); err != nil {
+			t.Fatal(err)
+		}
+
+		connectors[scope] = dc.(*PostgresConnector)
+	}
+
+	if n := len(connectors["postgres_users"].models); n != 1 {
+		t.Errorf("expected 1 model for postgres_users, got %d", n)
+	}
+
+	if n := len(connectors["postgres_orders"].models); n != 2 {
+		t.Errorf("expected 2 models for postgres_orders, got %d", n)
+	}
+}
@@ -0,0 +1,116 @@
+package postgres_connector
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// setupReplicas registers read replicas with dbresolver, so queries are
+// load-balanced across replicas and writes go to the primary. Callers can
+// force reading from the primary with db.Clauses(dbresolver.Write).
+//
+// Each entry of "replicas" can be a DSN, a "host:port" string or a map with
+// host and port, in which case other settings are shared with the primary.
+func (c *PostgresConnector) setupReplicas() error {
+
+	entries := make([]interface{}, 0)
+	switch v := viper.Get(c.getConfigPath("replicas")).(type) {
+	case string:
+		for _, e := range strings.Split(v, ",") {
+			if e = strings.TrimSpace(e); len(e) > 0 {
+				entries = append(entries, e)
+			}
+		}
+	case []string:
+		for _, e := range v {
+			entries = append(entries, e)
+		}
+	case []interface{}:
+		entries = v
+	}
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	dialectors := make([]gorm.Dialector, 0, len(entries))
+	for i, entry := range entries {
+
+		dsn, err := c.buildReplicaDSN(entry)
+		if err != nil {
+			return fmt.Errorf("postgres_connector: invalid replica #%d: %w", i, err)
+		}
+
+		dialectors = append(dialectors, postgres.Open(dsn))
+	}
+
+	c.resolver = dbresolver.Register(dbresolver.Config{
+		Replicas: dialectors,
+		Policy:   dbresolver.RandomPolicy{},
+	})
+
+	if err := c.db.Use(c.resolver); err != nil {
+		return err
+	}
+
+	c.logger.Info("Registered read replicas",
+		zap.Int("replicas", len(dialectors)),
+	)
+
+	return nil
+}
+
+func (c *PostgresConnector) buildReplicaDSN(entry interface{}) (string, error) {
+
+	defaultPort := viper.GetInt(c.getConfigPath("port"))
+
+	switch v := entry.(type) {
+	case string:
+
+		// Full DSN
+		if strings.Contains(v, "=") || strings.Contains(v, "://") {
+			return v, nil
+		}
+
+		host, portStr, err := net.SplitHostPort(v)
+		if err != nil {
+			return c.buildDSN(v, defaultPort)
+		}
+
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return "", err
+		}
+
+		return c.buildDSN(host, port)
+
+	case map[string]interface{}:
+
+		host, _ := v["host"].(string)
+		if len(host) == 0 {
+			return "", fmt.Errorf("host is required")
+		}
+
+		port := defaultPort
+		if p, ok := v["port"]; ok {
+			n, err := strconv.Atoi(fmt.Sprint(p))
+			if err != nil {
+				return "", err
+			}
+
+			port = n
+		}
+
+		return c.buildDSN(host, port)
+	}
+
+	return "", fmt.Errorf("unsupported entry type %T", entry)
+}
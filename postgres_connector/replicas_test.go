@@ -0,0 +1,63 @@
+package postgres_connector
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestStopClosesReplicas(t *testing.T) {
+
+	c := &PostgresConnector{
+		logger: zap.NewNop(),
+		scope:  "postgres_replicas",
+	}
+	c.initDefaultConfigs()
+
+	viper.Set(c.getConfigPath("replicas"), "replica-1:5432,replica-2:5432")
+
+	// Pools are created lazily, so no server is needed
+	db, err := gorm.Open(postgres.Open("host=localhost"), &gorm.Config{
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.db = db
+
+	if err := c.setupReplicas(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.onStop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	pools := 0
+	c.resolver.Call(func(pool gorm.ConnPool) error {
+
+		sqlDB, ok := pool.(*sql.DB)
+		if !ok {
+			t.Fatalf("unexpected connection pool %T", pool)
+		}
+
+		pools++
+
+		if err := sqlDB.Ping(); err == nil || err.Error() != "sql: database is closed" {
+			t.Errorf("expected connection pool to be closed: %v", err)
+		}
+
+		return nil
+	})
+
+	// Primary database and two replicas
+	if pools != 3 {
+		t.Errorf("expected 3 connection pools, got %d", pools)
+	}
+}
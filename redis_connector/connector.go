@@ -2,28 +2,62 @@ package redis_connector
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/spf13/viper"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
-	"github.com/go-redis/redis/v8"
 )
 
 var logger *zap.Logger
 
 const (
-	DefaultHost     = "0.0.0.0"
-	DefaultPort     = 6379
-	DefaultDB       = 0
-	DefaultPassword = ""
+	DefaultHost       = "0.0.0.0"
+	DefaultPort       = 6379
+	DefaultDB         = 0
+	DefaultUsername   = ""
+	DefaultPassword   = ""
+	DefaultMode       = ModeSingle
+	DefaultTLSEnabled = false
+	DefaultKeyPrefix  = ""
+
+	// Zero values fall back to defaults of go-redis
+	DefaultPoolSize     = 0 // 10 connections per CPU
+	DefaultMinIdleConns = 0
+	DefaultDialTimeout  = 5 * time.Second
+	DefaultReadTimeout  = 3 * time.Second
+	DefaultWriteTimeout = 3 * time.Second
+	DefaultMaxRetries   = 3
+
+	DefaultHealthInterval = 10 * time.Second
+)
+
+const KeySeparator = ":"
+
+const (
+	ModeSingle   = "single"
+	ModeCluster  = "cluster"
+	ModeSentinel = "sentinel"
 )
 
 type RedisConnector struct {
-	params Params
-	logger *zap.Logger
-	client *redis.Client
-	scope  string
+	params  Params
+	logger  *zap.Logger
+	client  redis.UniversalClient
+	scope   string
+	healthy atomic.Bool
+
+	keyPrefix string
+
+	cancelHealthCheck context.CancelFunc
+	healthCheckDone   chan struct{}
 }
 
 type Params struct {
@@ -75,47 +109,244 @@ func (c *RedisConnector) getConfigPath(key string) string {
 func (c *RedisConnector) initDefaultConfigs() {
 	viper.SetDefault(c.getConfigPath("host"), DefaultHost)
 	viper.SetDefault(c.getConfigPath("port"), DefaultPort)
+	viper.SetDefault(c.getConfigPath("username"), DefaultUsername)
 	viper.SetDefault(c.getConfigPath("password"), DefaultPassword)
 	viper.SetDefault(c.getConfigPath("db"), DefaultDB)
+	viper.SetDefault(c.getConfigPath("mode"), DefaultMode)
+	viper.SetDefault(c.getConfigPath("tls.enabled"), DefaultTLSEnabled)
+	viper.SetDefault(c.getConfigPath("key_prefix"), DefaultKeyPrefix)
+	viper.SetDefault(c.getConfigPath("pool_size"), DefaultPoolSize)
+	viper.SetDefault(c.getConfigPath("min_idle_conns"), DefaultMinIdleConns)
+	viper.SetDefault(c.getConfigPath("dial_timeout"), DefaultDialTimeout)
+	viper.SetDefault(c.getConfigPath("read_timeout"), DefaultReadTimeout)
+	viper.SetDefault(c.getConfigPath("write_timeout"), DefaultWriteTimeout)
+	viper.SetDefault(c.getConfigPath("max_retries"), DefaultMaxRetries)
+	viper.SetDefault(c.getConfigPath("health_interval"), DefaultHealthInterval)
 }
 
 func (c *RedisConnector) onStart(ctx context.Context) error {
 
 	// Prparing configurations
-	host := viper.GetString(c.getConfigPath("host"))
-	port := viper.GetInt(c.getConfigPath("port"))
-	password := viper.GetString(c.getConfigPath("password"))
-	db := viper.GetInt(c.getConfigPath("db"))
+	mode := viper.GetString(c.getConfigPath("mode"))
 
 	logger.Info("Starting RedisConnector",
-		zap.String("host", host),
-		zap.Int("port", port),
-		zap.Int("db", db),
+		zap.String("mode", mode),
 	)
 
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%v:%v", host, port), // use default Addr
-		Password: password, // no password set
-		DB:       db, // use default DB
-	})
+	opts, err := c.buildOptions()
+	if err != nil {
+		return err
+	}
+
+	var rdb redis.UniversalClient
+	switch mode {
+	case ModeSingle:
+		rdb = c.newSingleClient(opts)
+	case ModeCluster:
+		client, err := c.newClusterClient(opts)
+		if err != nil {
+			return err
+		}
 
-	_, err := rdb.Ping(ctx).Result()
+		rdb = client
+	case ModeSentinel:
+		client, err := c.newFailoverClient(opts)
+		if err != nil {
+			return err
+		}
+
+		rdb = client
+	default:
+		return fmt.Errorf("redis_connector: unsupported mode \"%s\"", mode)
+	}
+
+	_, err = rdb.Ping(ctx).Result()
 	if err != nil {
+		rdb.Close()
 		return err
 	}
 
 	c.client = rdb
+	c.keyPrefix = strings.TrimSuffix(viper.GetString(c.getConfigPath("key_prefix")), KeySeparator)
+
+	c.startHealthCheck(viper.GetDuration(c.getConfigPath("health_interval")))
 
 	return nil
 }
 
+// buildOptions prepares options shared by all modes
+func (c *RedisConnector) buildOptions() (*redis.UniversalOptions, error) {
+
+	tlsConfig, err := c.buildTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &redis.UniversalOptions{
+		Username:     viper.GetString(c.getConfigPath("username")),
+		Password:     viper.GetString(c.getConfigPath("password")),
+		DB:           viper.GetInt(c.getConfigPath("db")),
+		TLSConfig:    tlsConfig,
+		PoolSize:     viper.GetInt(c.getConfigPath("pool_size")),
+		MinIdleConns: viper.GetInt(c.getConfigPath("min_idle_conns")),
+		DialTimeout:  viper.GetDuration(c.getConfigPath("dial_timeout")),
+		ReadTimeout:  viper.GetDuration(c.getConfigPath("read_timeout")),
+		WriteTimeout: viper.GetDuration(c.getConfigPath("write_timeout")),
+		MaxRetries:   viper.GetInt(c.getConfigPath("max_retries")),
+	}
+
+	return opts, nil
+}
+
+func (c *RedisConnector) newSingleClient(opts *redis.UniversalOptions) *redis.Client {
+
+	host := viper.GetString(c.getConfigPath("host"))
+	port := viper.GetInt(c.getConfigPath("port"))
+
+	opts.Addrs = []string{fmt.Sprintf("%v:%v", host, port)}
+
+	client := redis.NewClient(opts.Simple())
+
+	logger.Info("Connecting to Redis",
+		zap.String("host", host),
+		zap.Int("port", port),
+		zap.Int("db", opts.DB),
+		zap.Int("pool_size", client.Options().PoolSize),
+	)
+
+	return client
+}
+
+func (c *RedisConnector) newClusterClient(opts *redis.UniversalOptions) (*redis.ClusterClient, error) {
+
+	addrs := viper.GetStringSlice(c.getConfigPath("addrs"))
+
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("redis_connector: addrs is required in cluster mode")
+	}
+
+	opts.Addrs = addrs
+
+	client := redis.NewClusterClient(opts.Cluster())
+
+	logger.Info("Connecting to Redis cluster",
+		zap.Strings("addrs", addrs),
+		zap.Int("pool_size", client.Options().PoolSize),
+	)
+
+	return client, nil
+}
+
+// newFailoverClient creates client which discovers master via Sentinel and
+// follows the new master after failover.
+func (c *RedisConnector) newFailoverClient(opts *redis.UniversalOptions) (*redis.Client, error) {
+
+	masterName := viper.GetString(c.getConfigPath("master_name"))
+	sentinelAddrs := viper.GetStringSlice(c.getConfigPath("sentinel_addrs"))
+
+	if len(masterName) == 0 {
+		return nil, fmt.Errorf("redis_connector: master_name is required in sentinel mode")
+	}
+
+	if len(sentinelAddrs) == 0 {
+		return nil, fmt.Errorf("redis_connector: sentinel_addrs is required in sentinel mode")
+	}
+
+	opts.MasterName = masterName
+	opts.Addrs = sentinelAddrs
+	opts.SentinelPassword = viper.GetString(c.getConfigPath("sentinel_password"))
+
+	client := redis.NewFailoverClient(opts.Failover())
+
+	logger.Info("Connecting to Redis via Sentinel",
+		zap.String("master_name", masterName),
+		zap.Strings("sentinel_addrs", sentinelAddrs),
+		zap.Int("db", opts.DB),
+		zap.Int("pool_size", client.Options().PoolSize),
+	)
+
+	return client, nil
+}
+
+func (c *RedisConnector) buildTLSConfig() (*tls.Config, error) {
+
+	if !viper.GetBool(c.getConfigPath("tls.enabled")) {
+		return nil, nil
+	}
+
+	caFile := viper.GetString(c.getConfigPath("tls.ca_file"))
+	certFile := viper.GetString(c.getConfigPath("tls.cert_file"))
+	keyFile := viper.GetString(c.getConfigPath("tls.key_file"))
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: viper.GetBool(c.getConfigPath("tls.insecure_skip_verify")),
+	}
+
+	if len(caFile) > 0 {
+
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("redis_connector: failed to load CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("redis_connector: no valid certificate found in CA file \"%s\"", caFile)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	// Client certificate
+	if len(certFile) > 0 || len(keyFile) > 0 {
+
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("redis_connector: failed to load client certificate: %w", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
 func (c *RedisConnector) onStop(ctx context.Context) error {
 
+	c.stopHealthCheck()
+
 	logger.Info("Stopped RedisConnector")
 
 	return c.client.Close()
 }
 
+// GetClient returns client of single and sentinel modes. It panics in
+// cluster mode, use GetUniversalClient instead for working with all modes.
 func (c *RedisConnector) GetClient() *redis.Client {
+
+	if c.client == nil {
+		return nil
+	}
+
+	client, ok := c.client.(*redis.Client)
+	if !ok {
+		panic(fmt.Sprintf("redis_connector: GetClient is not available in %s mode of \"%s\", use GetUniversalClient instead", ModeCluster, c.scope))
+	}
+
+	return client
+}
+
+func (c *RedisConnector) GetUniversalClient() redis.UniversalClient {
 	return c.client
 }
+
+// Key joins key prefix configured at startup and parts with ":" separator.
+func (c *RedisConnector) Key(parts ...string) string {
+
+	if len(c.keyPrefix) > 0 {
+		parts = append([]string{c.keyPrefix}, parts...)
+	}
+
+	return strings.Join(parts, KeySeparator)
+}
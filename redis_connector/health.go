@@ -0,0 +1,81 @@
+package redis_connector
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// startHealthCheck pings Redis periodically in background to keep track of
+// connection status.
+func (c *RedisConnector) startHealthCheck(interval time.Duration) {
+
+	c.healthy.Store(true)
+
+	if interval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancelHealthCheck = cancel
+	c.healthCheckDone = make(chan struct{})
+
+	go func() {
+
+		defer close(c.healthCheckDone)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.checkHealth(ctx, interval)
+			}
+		}
+	}()
+}
+
+func (c *RedisConnector) checkHealth(ctx context.Context, timeout time.Duration) {
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := c.client.Ping(ctx).Err()
+
+	// Stopping
+	if ctx.Err() == context.Canceled {
+		return
+	}
+
+	healthy := err == nil
+	if c.healthy.Swap(healthy) == healthy {
+		return
+	}
+
+	if !healthy {
+		c.logger.Error("Lost connection to Redis", zap.Error(err))
+		return
+	}
+
+	c.logger.Info("Reconnected to Redis")
+}
+
+func (c *RedisConnector) stopHealthCheck() {
+
+	if c.cancelHealthCheck == nil {
+		return
+	}
+
+	c.cancelHealthCheck()
+	<-c.healthCheckDone
+	c.cancelHealthCheck = nil
+}
+
+// IsHealthy returns whether the latest health check succeeded.
+func (c *RedisConnector) IsHealthy() bool {
+	return c.healthy.Load()
+}